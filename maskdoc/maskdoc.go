@@ -0,0 +1,511 @@
+// Package maskdoc loads and stores MountOptsMasks as versioned JSON or YAML
+// documents, such as BOSH job properties.
+package maskdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	"code.cloudfoundry.org/volume-mount-options/validators"
+	"go.yaml.in/yaml/v3"
+)
+
+// Version is the document version written by Marshal. Documents with a
+// newer version are rejected.
+const Version = 1
+
+// Document is the declarative form of a MountOptsMask. Normalizers name
+// built-in normalizers, applied in order, and extend the default ones.
+type Document struct {
+	Version     int                        `json:"version" yaml:"version"`
+	Allowed     []string                   `json:"allowed,omitempty" yaml:"allowed,omitempty"`
+	Defaults    map[string]interface{}     `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	Aliases     map[string]string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Ignored     []string                   `json:"ignored,omitempty" yaml:"ignored,omitempty"`
+	Mandatory   []string                   `json:"mandatory,omitempty" yaml:"mandatory,omitempty"`
+	Forbidden   map[string]string          `json:"forbidden,omitempty" yaml:"forbidden,omitempty"`
+	Locked      map[string]interface{}     `json:"locked,omitempty" yaml:"locked,omitempty"`
+	Scopes      map[string]vmo.OptionScope `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Types       map[string]Type            `json:"types,omitempty" yaml:"types,omitempty"`
+	Normalizers map[string][]string        `json:"normalizers,omitempty" yaml:"normalizers,omitempty"`
+	Constraints []Constraint               `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Validators  []validators.Spec          `json:"validators,omitempty" yaml:"validators,omitempty"`
+	Policy      Policy                     `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+// Type is the declarative form of an OptionType. Unit is a Go duration such
+// as "1s".
+type Type struct {
+	Kind    vmo.OptionKind `json:"kind" yaml:"kind"`
+	Min     *int64         `json:"min,omitempty" yaml:"min,omitempty"`
+	Max     *int64         `json:"max,omitempty" yaml:"max,omitempty"`
+	Values  []string       `json:"values,omitempty" yaml:"values,omitempty"`
+	Pattern string         `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Unit    string         `json:"unit,omitempty" yaml:"unit,omitempty"`
+}
+
+// Condition is the declarative form of a vmo.Condition. An empty Op means
+// "present".
+type Condition struct {
+	Key   string          `json:"key" yaml:"key"`
+	Op    vmo.ConditionOp `json:"op,omitempty" yaml:"op,omitempty"`
+	Value string          `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+type Constraint struct {
+	Kind vmo.ConstraintKind `json:"kind" yaml:"kind"`
+	If   *Condition         `json:"if,omitempty" yaml:"if,omitempty"`
+	Then []Condition        `json:"then" yaml:"then"`
+}
+
+// Policy holds the mask's policies by name. Empty fields use the mask's
+// defaults.
+type Policy struct {
+	SloppyMount          bool   `json:"sloppy_mount,omitempty" yaml:"sloppy_mount,omitempty"`
+	Locked               string `json:"locked,omitempty" yaml:"locked,omitempty"`
+	UnsafeValues         string `json:"unsafe_values,omitempty" yaml:"unsafe_values,omitempty"`
+	AliasPrecedence      string `json:"alias_precedence,omitempty" yaml:"alias_precedence,omitempty"`
+	UnknownOptions       string `json:"unknown_options,omitempty" yaml:"unknown_options,omitempty"`
+	UnknownOptionsPrefix string `json:"unknown_options_prefix,omitempty" yaml:"unknown_options_prefix,omitempty"`
+}
+
+var (
+	lockedPolicies = map[string]vmo.LockedPolicy{
+		"override": vmo.OverrideLocked,
+		"reject":   vmo.RejectLockedOverride,
+	}
+	unsafeValuePolicies = map[string]vmo.UnsafeValuePolicy{
+		"reject": vmo.RejectUnsafeValues,
+		"allow":  vmo.AllowUnsafeValues,
+	}
+	aliasPrecedences = map[string]vmo.AliasPrecedence{
+		"canonical-wins": vmo.CanonicalWins,
+		"alias-wins":     vmo.AliasWins,
+		"reject":         vmo.RejectAliasConflict,
+	}
+	unknownOptionModes = map[string]vmo.UnknownOptionMode{
+		"default":               vmo.UnknownOptionsDefault,
+		"reject":                vmo.RejectUnknownOptions,
+		"drop":                  vmo.DropUnknownOptions,
+		"drop-with-warning":     vmo.DropUnknownOptionsWithWarning,
+		"pass-through":          vmo.PassThroughUnknownOptions,
+		"pass-through-prefixed": vmo.PassThroughPrefixedOptions,
+	}
+	normalizers = map[string]func() vmo.Normalizer{
+		"bool-as-int":      vmo.BoolAsInt,
+		"bool-as-yes-no":   vmo.BoolAsYesNo,
+		"lowercase":        vmo.Lowercase,
+		"trim":             vmo.Trim,
+		"canonical-number": vmo.CanonicalNumber,
+	}
+)
+
+// Load parses a JSON or YAML document and builds its mask.
+func Load(data []byte) (vmo.MountOptsMask, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return vmo.MountOptsMask{}, err
+	}
+	return doc.Mask()
+}
+
+// Parse parses a JSON or YAML document. Unknown fields are rejected.
+func Parse(data []byte) (*Document, error) {
+	doc := &Document{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		decoder.UseNumber()
+		if err := decoder.Decode(doc); err != nil {
+			return nil, fmt.Errorf("invalid mask document: %w", err)
+		}
+		doc.Defaults = yamlNumbers(doc.Defaults)
+		doc.Locked = yamlNumbers(doc.Locked)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(doc); err != nil {
+			return nil, fmt.Errorf("invalid mask document: %w", err)
+		}
+	}
+
+	if err := doc.checkVersion(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// yamlNumbers converts the JSON numbers of values to the types the YAML
+// decoder produces, so that both formats describe the same mask and large
+// integers are not rendered as floats.
+func yamlNumbers(values map[string]interface{}) map[string]interface{} {
+	for k, v := range values {
+		n, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := strconv.ParseInt(n.String(), 10, 0); err == nil {
+			values[k] = int(i)
+		} else if f, err := n.Float64(); err == nil {
+			values[k] = f
+		} else {
+			values[k] = n.String()
+		}
+	}
+	return values
+}
+
+func (d *Document) checkVersion() error {
+	switch {
+	case d.Version == 0:
+		return errors.New("mask document has no version")
+	case d.Version > Version:
+		return fmt.Errorf("unsupported mask document version %d", d.Version)
+	}
+	return nil
+}
+
+// Mask builds the mask described by d, and lints it like NewMask.
+func (d *Document) Mask() (vmo.MountOptsMask, error) {
+	if err := d.checkVersion(); err != nil {
+		return vmo.MountOptsMask{}, err
+	}
+
+	options := []vmo.MaskOption{
+		vmo.WithAllowed(d.Allowed...),
+		vmo.WithDefaults(d.Defaults),
+		vmo.WithKeyPerms(d.Aliases),
+		vmo.WithIgnored(d.Ignored...),
+		vmo.WithMandatory(d.Mandatory...),
+	}
+
+	for _, k := range sortedKeys(d.Forbidden) {
+		options = append(options, vmo.WithForbidden(k, d.Forbidden[k]))
+	}
+	for _, k := range sortedKeys(d.Locked) {
+		options = append(options, vmo.WithLocked(k, d.Locked[k]))
+	}
+	for _, k := range sortedKeys(d.Scopes) {
+		options = append(options, vmo.WithScope(d.Scopes[k], k))
+	}
+
+	for _, k := range sortedKeys(d.Types) {
+		t, err := d.Types[k].optionType()
+		if err != nil {
+			return vmo.MountOptsMask{}, fmt.Errorf("type of %s: %w", k, err)
+		}
+		options = append(options, vmo.WithType(k, t))
+	}
+
+	if len(d.Normalizers) > 0 {
+		defaults := vmo.DefaultNormalizers()
+		for k, n := range defaults {
+			defaults[k] = named(n, nil)
+		}
+		options = append(options, vmo.WithNormalizers(defaults))
+		for _, k := range sortedKeys(d.Normalizers) {
+			var chain []vmo.Normalizer
+			for _, name := range d.Normalizers[k] {
+				n, ok := normalizers[name]
+				if !ok {
+					return vmo.MountOptsMask{}, fmt.Errorf("unknown normalizer %q for %s", name, k)
+				}
+				chain = append(chain, n())
+			}
+			names := append([]string{}, d.Normalizers[k]...)
+			options = append(options, vmo.WithNormalizer(k, named(vmo.ChainNormalizers(chain...), names)))
+		}
+	}
+
+	for _, c := range d.Constraints {
+		options = append(options, vmo.WithConstraint(c.constraint()))
+	}
+
+	for _, spec := range d.Validators {
+		v, err := spec.Build()
+		if err != nil {
+			return vmo.MountOptsMask{}, err
+		}
+		options = append(options, vmo.WithValidator(v))
+	}
+
+	policyOptions, err := d.Policy.options()
+	if err != nil {
+		return vmo.MountOptsMask{}, err
+	}
+
+	return vmo.NewMask(append(options, policyOptions...)...)
+}
+
+func (t Type) optionType() (vmo.OptionType, error) {
+	optionType := vmo.OptionType{Kind: t.Kind, Min: t.Min, Max: t.Max, Values: t.Values}
+	if t.Pattern != "" {
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return vmo.OptionType{}, err
+		}
+		optionType.Pattern = re
+	}
+	if t.Unit != "" {
+		unit, err := time.ParseDuration(t.Unit)
+		if err != nil {
+			return vmo.OptionType{}, err
+		}
+		optionType.Unit = unit
+	}
+	return optionType, nil
+}
+
+func (c Condition) condition() vmo.Condition {
+	op := c.Op
+	if op == "" {
+		op = vmo.OpPresent
+	}
+	return vmo.Condition{Key: c.Key, Op: op, Value: c.Value}
+}
+
+func (c Constraint) constraint() vmo.Constraint {
+	constraint := vmo.Constraint{Kind: c.Kind}
+	if c.If != nil {
+		constraint.If = c.If.condition()
+	}
+	for _, cond := range c.Then {
+		constraint.Then = append(constraint.Then, cond.condition())
+	}
+	return constraint
+}
+
+func (p Policy) options() ([]vmo.MaskOption, error) {
+	var options []vmo.MaskOption
+	if p.SloppyMount {
+		options = append(options, vmo.WithSloppy(true))
+	}
+
+	if p.Locked != "" {
+		policy, ok := lockedPolicies[p.Locked]
+		if !ok {
+			return nil, fmt.Errorf("unknown locked policy %q", p.Locked)
+		}
+		options = append(options, vmo.WithLockedPolicy(policy))
+	}
+	if p.UnsafeValues != "" {
+		policy, ok := unsafeValuePolicies[p.UnsafeValues]
+		if !ok {
+			return nil, fmt.Errorf("unknown unsafe values policy %q", p.UnsafeValues)
+		}
+		options = append(options, vmo.WithUnsafeValues(policy))
+	}
+	if p.AliasPrecedence != "" {
+		precedence, ok := aliasPrecedences[p.AliasPrecedence]
+		if !ok {
+			return nil, fmt.Errorf("unknown alias precedence %q", p.AliasPrecedence)
+		}
+		options = append(options, vmo.WithAliasPrecedence(precedence))
+	}
+	if p.UnknownOptions != "" {
+		mode, ok := unknownOptionModes[p.UnknownOptions]
+		if !ok {
+			return nil, fmt.Errorf("unknown policy for unknown options %q", p.UnknownOptions)
+		}
+		options = append(options, vmo.WithUnknownOptions(vmo.UnknownOptionPolicy{Mode: mode, Prefix: p.UnknownOptionsPrefix}))
+	}
+	return options, nil
+}
+
+// FromMask describes mask as a Document. It fails for masks with
+// normalizers other than those loaded from a document, MountOptsValidations,
+// or validators not built from a validators.Spec, as these cannot be
+// described.
+func FromMask(mask vmo.MountOptsMask) (*Document, error) {
+	normalizerNames, ok := describeNormalizers(mask)
+	if !ok {
+		return nil, errors.New("mask normalizers cannot be described")
+	}
+	if len(mask.OptsValidationFunc) > 0 {
+		return nil, errors.New("mask MountOptsValidations cannot be described")
+	}
+
+	doc := &Document{
+		Version:   Version,
+		Allowed:   mask.Allowed,
+		Defaults:  mask.Defaults,
+		Aliases:   mask.KeyPerms,
+		Ignored:   mask.Ignored,
+		Mandatory: mask.Mandatory,
+		Forbidden: mask.Forbidden,
+		Locked:    mask.Locked,
+		Scopes:    mask.Scopes,
+		Policy: Policy{
+			SloppyMount:          mask.SloppyMount,
+			Locked:               nameOf(lockedPolicies, mask.LockedPolicy, vmo.OverrideLocked),
+			UnsafeValues:         nameOf(unsafeValuePolicies, mask.UnsafeValues, vmo.RejectUnsafeValues),
+			AliasPrecedence:      nameOf(aliasPrecedences, mask.AliasPrecedence, vmo.CanonicalWins),
+			UnknownOptions:       nameOf(unknownOptionModes, mask.UnknownOptions.Mode, vmo.UnknownOptionsDefault),
+			UnknownOptionsPrefix: mask.UnknownOptions.Prefix,
+		},
+	}
+
+	for _, k := range sortedKeys(mask.Types) {
+		if doc.Types == nil {
+			doc.Types = make(map[string]Type)
+		}
+		doc.Types[k] = typeOf(mask.Types[k])
+	}
+
+	if len(normalizerNames) > 0 {
+		doc.Normalizers = normalizerNames
+	}
+
+	for _, c := range mask.Constraints {
+		doc.Constraints = append(doc.Constraints, constraintOf(c))
+	}
+
+	for _, v := range mask.ValidationFunc {
+		spec, ok := validators.SpecOf(v)
+		if !ok {
+			return nil, errors.New("mask validator cannot be described; build it from a validators.Spec")
+		}
+		doc.Validators = append(doc.Validators, spec)
+	}
+
+	return doc, nil
+}
+
+// Marshal describes mask as a JSON document.
+func Marshal(mask vmo.MountOptsMask) ([]byte, error) {
+	doc, err := FromMask(mask)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// MarshalYAML describes mask as a YAML document.
+func MarshalYAML(mask vmo.MountOptsMask) ([]byte, error) {
+	doc, err := FromMask(mask)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// describeNormalizers returns the names of the normalizers that a document
+// added to the default ones of mask. It fails unless every normalizer of
+// mask was loaded from a document, and the default ones are all present.
+func describeNormalizers(mask vmo.MountOptsMask) (map[string][]string, bool) {
+	if mask.Normalizers == nil {
+		return nil, true
+	}
+	for k := range vmo.DefaultNormalizers() {
+		if _, ok := mask.Normalizers[k]; !ok {
+			return nil, false
+		}
+	}
+
+	names := make(map[string][]string)
+	for k, n := range mask.Normalizers {
+		nn, ok := namesOf(n)
+		if !ok {
+			return nil, false
+		}
+		if nn.names != nil {
+			names[k] = nn.names
+		}
+	}
+	return names, true
+}
+
+// namedNormalizer is returned by a normalizer loaded from a document when it
+// is asked for its names. Default normalizers have no names.
+type namedNormalizer struct {
+	names []string
+}
+
+func (n namedNormalizer) Error() string {
+	return fmt.Sprintf("normalizer %v", n.names)
+}
+
+// normalizerQuery asks a named normalizer for its names.
+type normalizerQuery struct{}
+
+// named wraps n so that namesOf recognizes it. Normalizers replaced after a
+// mask was loaded are not recognized, so stale names cannot be described.
+func named(n vmo.Normalizer, names []string) vmo.Normalizer {
+	return func(value interface{}) (string, error) {
+		if _, ok := value.(normalizerQuery); ok {
+			return "", namedNormalizer{names: names}
+		}
+		return n(value)
+	}
+}
+
+func namesOf(n vmo.Normalizer) (nn namedNormalizer, ok bool) {
+	// Other normalizers may not expect a query.
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	_, err := n(normalizerQuery{})
+	return nn, errors.As(err, &nn)
+}
+
+func typeOf(t vmo.OptionType) Type {
+	typ := Type{Kind: t.Kind, Min: t.Min, Max: t.Max, Values: t.Values}
+	if t.Pattern != nil {
+		typ.Pattern = t.Pattern.String()
+	}
+	if t.Unit != 0 {
+		typ.Unit = t.Unit.String()
+	}
+	return typ
+}
+
+func conditionOf(c vmo.Condition) Condition {
+	cond := Condition{Key: c.Key, Op: c.Op, Value: c.Value}
+	if cond.Op == vmo.OpPresent {
+		cond.Op = ""
+	}
+	return cond
+}
+
+func constraintOf(c vmo.Constraint) Constraint {
+	constraint := Constraint{Kind: c.Kind}
+	if c.Kind != vmo.MutuallyExclusiveConstraint {
+		cond := conditionOf(c.If)
+		constraint.If = &cond
+	}
+	for _, cond := range c.Then {
+		constraint.Then = append(constraint.Then, conditionOf(cond))
+	}
+	return constraint
+}
+
+// nameOf returns the name of value in names, or "" for the default value.
+func nameOf[V comparable](names map[string]V, value, defaultValue V) string {
+	if value == defaultValue {
+		return ""
+	}
+	for _, name := range sortedKeys(names) {
+		if names[name] == value {
+			return name
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,13 @@
+package maskdoc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMaskdoc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Maskdoc Suite")
+}
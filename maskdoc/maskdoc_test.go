@@ -0,0 +1,240 @@
+package maskdoc_test
+
+import (
+	"time"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	"code.cloudfoundry.org/volume-mount-options/maskdoc"
+	"code.cloudfoundry.org/volume-mount-options/utils"
+	"code.cloudfoundry.org/volume-mount-options/validators"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const yamlDocument = `
+version: 1
+allowed: [source, vers, sec, uid, gid, timeo, "x-systemd.*"]
+defaults:
+  vers: 4.1
+  timeo: 600
+aliases:
+  version: vers
+mandatory: [source]
+forbidden:
+  suid: setuid binaries are not allowed
+locked:
+  nodev: true
+scopes:
+  source: instance
+  uid: binding
+types:
+  timeo:
+    kind: duration
+    unit: 100ms
+  sec:
+    kind: enum
+    values: [sys, krb5, krb5i, krb5p]
+normalizers:
+  sec: [trim, lowercase]
+constraints:
+  - kind: requires
+    if: {key: gid}
+    then: [{key: uid}]
+validators:
+  - name: numeric-id
+    keys: [uid, gid]
+policy:
+  locked: reject
+  unknown_options: pass-through-prefixed
+  unknown_options_prefix: x-
+`
+
+var _ = Describe("Maskdoc", func() {
+	Describe("#Load", func() {
+		It("should build the mask described by a YAML document", func() {
+			mask, err := maskdoc.Load([]byte(yamlDocument))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mask.Allowed).To(ContainElement("x-systemd.*"))
+			Expect(mask.KeyPerms).To(Equal(map[string]string{"version": "vers"}))
+			Expect(mask.Scopes).To(Equal(map[string]vmo.OptionScope{"source": vmo.ScopeInstance, "uid": vmo.ScopeBinding}))
+			Expect(mask.Types["timeo"]).To(Equal(vmo.DurationType(100 * time.Millisecond)))
+			Expect(mask.LockedPolicy).To(Equal(vmo.RejectLockedOverride))
+			Expect(mask.UnknownOptions).To(Equal(vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}))
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{
+				"source":      "//server/share",
+				"sec":         " KRB5I ",
+				"timeo":       "30s",
+				"uid":         "1000",
+				"x-vendor.io": "fast",
+			}, mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{
+				"source":      "//server/share",
+				"vers":        4.1,
+				"sec":         "krb5i",
+				"timeo":       "300",
+				"uid":         "1000",
+				"nodev":       true,
+				"x-vendor.io": "fast",
+			}))
+
+			_, err = vmo.NewMountOpts(map[string]interface{}{"source": "//server/share", "gid": "staff"}, mask)
+			Expect(err).To(MatchError(ContainSubstring("gid: must be a numeric id")))
+			Expect(err).To(MatchError(ContainSubstring("gid requires uid")))
+		})
+
+		It("should build the mask described by a JSON document", func() {
+			mask, err := maskdoc.Load([]byte(`{"version": 1, "allowed": ["uid"], "defaults": {"timeo": 600}, "policy": {"sloppy_mount": true}}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mask.Allowed).To(Equal([]string{"uid"}))
+			Expect(mask.SloppyMount).To(BeTrue())
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{}, mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{"timeo": 600}))
+		})
+
+		It("should read JSON and YAML numbers alike", func() {
+			fromJSON, err := maskdoc.Load([]byte(`{"version": 1, "allowed": ["rsize", "vers"], "defaults": {"rsize": 1048576, "vers": 4.1}, "locked": {"wsize": 1048576}}`))
+			Expect(err).NotTo(HaveOccurred())
+			fromYAML, err := maskdoc.Load([]byte("version: 1\nallowed: [rsize, vers]\ndefaults: {rsize: 1048576, vers: 4.1}\nlocked: {wsize: 1048576}"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fromJSON.Defaults).To(Equal(fromYAML.Defaults))
+			Expect(fromJSON.Locked).To(Equal(fromYAML.Locked))
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{}, fromJSON)
+			Expect(err).NotTo(HaveOccurred())
+			options, err := utils.OptionMapToString(opts, "=")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options).To(Equal("rsize=1048576,vers=4.1,wsize=1048576"))
+		})
+
+		DescribeTable("with invalid documents",
+			func(document string, message string) {
+				_, err := maskdoc.Load([]byte(document))
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("no version", "allowed: [uid]", "mask document has no version"),
+			Entry("a newer version", "version: 2", "unsupported mask document version 2"),
+			Entry("an unknown field", "version: 1\nalowed: [uid]", "field alowed not found"),
+			Entry("an unknown JSON field", `{"version": 1, "alowed": ["uid"]}`, `unknown field "alowed"`),
+			Entry("an unknown validator", "version: 1\nvalidators: [{name: bogus}]", `unknown validator "bogus"`),
+			Entry("an unknown normalizer", "version: 1\nnormalizers: {sec: [upper]}", `unknown normalizer "upper" for sec`),
+			Entry("an unknown policy", "version: 1\npolicy: {locked: maybe}", `unknown locked policy "maybe"`),
+			Entry("an invalid type pattern", "version: 1\ntypes: {sec: {kind: string, pattern: '('}}", "type of sec"),
+			Entry("an unknown constraint kind", "version: 1\nconstraints: [{kind: implies, if: {key: uid}, then: [{key: gid}]}]", `unknown constraint kind "implies"`),
+			Entry("a bad constraint glob", "version: 1\nconstraints: [{kind: requires, if: {key: uid}, then: [{key: gid, op: '=', value: '[0'}]}]", `gid: invalid pattern "[0"`),
+			Entry("a contradictory mask", "version: 1\nmandatory: [uid]\nignored: [uid]", "uid is both Mandatory and Ignored"),
+		)
+	})
+
+	Describe("#Marshal", func() {
+		var mask vmo.MountOptsMask
+
+		BeforeEach(func() {
+			numericID, err := validators.Spec{Name: "numeric-id", Keys: []string{"uid"}}.Build()
+			Expect(err).NotTo(HaveOccurred())
+
+			mask, err = vmo.NewMask(
+				vmo.WithAllowed("uid", "gid", "vers"),
+				vmo.WithDefault("vers", "3"),
+				vmo.WithAlias("UID", "uid"),
+				vmo.WithForbidden("suid", "no setuid"),
+				vmo.WithLocked("nodev", true),
+				vmo.WithScope(vmo.ScopeBinding, "uid"),
+				vmo.WithType("gid", vmo.IntRangeType(0, 65535)),
+				vmo.WithConstraint(
+					vmo.Requires(vmo.Present("gid"), vmo.Present("uid")),
+					vmo.MutuallyExclusive(vmo.Equals("vers", "3"), vmo.Present("gid")),
+				),
+				vmo.WithValidator(numericID),
+				vmo.WithUnsafeValues(vmo.AllowUnsafeValues),
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should write a JSON document", func() {
+			data, err := maskdoc.Marshal(mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(MatchJSON(`{
+				"version": 1,
+				"allowed": ["uid", "gid", "vers"],
+				"defaults": {"vers": "3"},
+				"aliases": {"UID": "uid"},
+				"forbidden": {"suid": "no setuid"},
+				"locked": {"nodev": true},
+				"scopes": {"uid": "binding"},
+				"types": {"gid": {"kind": "int", "min": 0, "max": 65535}},
+				"constraints": [
+					{"kind": "requires", "if": {"key": "gid"}, "then": [{"key": "uid"}]},
+					{"kind": "mutually-exclusive", "then": [{"key": "vers", "op": "=", "value": "3"}, {"key": "gid"}]}
+				],
+				"validators": [{"name": "numeric-id", "keys": ["uid"]}],
+				"policy": {"unsafe_values": "allow"}
+			}`))
+		})
+
+		It("should round-trip through JSON and YAML", func() {
+			data, err := maskdoc.Marshal(mask)
+			Expect(err).NotTo(HaveOccurred())
+			fromJSON, err := maskdoc.Load(data)
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err = maskdoc.MarshalYAML(mask)
+			Expect(err).NotTo(HaveOccurred())
+			fromYAML, err := maskdoc.Load(data)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, loaded := range []vmo.MountOptsMask{fromJSON, fromYAML} {
+				doc, err := maskdoc.FromMask(loaded)
+				Expect(err).NotTo(HaveOccurred())
+				expected, err := maskdoc.FromMask(mask)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(doc).To(Equal(expected))
+			}
+		})
+
+		It("should round-trip normalizers loaded from a document", func() {
+			loaded, err := maskdoc.Load([]byte(yamlDocument))
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := maskdoc.Marshal(loaded)
+			Expect(err).NotTo(HaveOccurred())
+			doc, err := maskdoc.Parse(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc.Normalizers).To(Equal(map[string][]string{"sec": {"trim", "lowercase"}}))
+
+			reloaded, err := doc.Mask()
+			Expect(err).NotTo(HaveOccurred())
+			opts, err := vmo.NewMountOpts(map[string]interface{}{"source": "//server/share", "sec": " KRB5I "}, reloaded)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(HaveKeyWithValue("sec", "krb5i"))
+		})
+
+		It("should fail for masks that cannot be described", func() {
+			mask.ValidationFunc = append(mask.ValidationFunc, validators.NonEmpty())
+			_, err := maskdoc.Marshal(mask)
+			Expect(err).To(MatchError(ContainSubstring("mask validator cannot be described")))
+
+			_, err = maskdoc.MarshalYAML(vmo.MountOptsMask{Normalizers: vmo.DefaultNormalizers()})
+			Expect(err).To(MatchError("mask normalizers cannot be described"))
+
+			loaded, err := maskdoc.Load([]byte(yamlDocument))
+			Expect(err).NotTo(HaveOccurred())
+			loaded.Normalizers["uid"] = vmo.Trim()
+			_, err = maskdoc.Marshal(loaded)
+			Expect(err).To(MatchError("mask normalizers cannot be described"))
+		})
+
+		It("should not describe normalizers replaced after loading", func() {
+			loaded, err := maskdoc.Load([]byte(yamlDocument))
+			Expect(err).NotTo(HaveOccurred())
+			loaded.Normalizers["sec"] = vmo.BoolAsYesNo()
+
+			_, err = maskdoc.Marshal(loaded)
+			Expect(err).To(MatchError("mask normalizers cannot be described"))
+		})
+	})
+})
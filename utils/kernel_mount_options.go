@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 )
 
+// ParseOptionStringToMap splits optionString on commas, and each option on
+// the first separator, the way the kernel does. Quotes and backslashes have
+// no special meaning; use ParseOptionString for values containing commas.
 func ParseOptionStringToMap(optionString, separator string) map[string]interface{} {
 	mountOpts := make(map[string]interface{}, 0)
 
@@ -25,3 +30,165 @@ func ParseOptionStringToMap(optionString, separator string) map[string]interface
 
 	return mountOpts
 }
+
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Msg, e.Offset)
+}
+
+// ParseOptionString parses a comma-separated list of options, each either a
+// bare flag or a key and value joined by separator. Values may be wrapped in
+// double quotes and any character may be backslash-escaped, so that values
+// can contain commas. Unquoted whitespace around keys and values is trimmed
+// and empty segments are skipped.
+func ParseOptionString(optionString, separator string) (map[string]interface{}, error) {
+	mountOpts := make(map[string]interface{})
+
+	var (
+		token        strings.Builder
+		keep         int
+		significant  bool
+		key          string
+		haveKey      bool
+		segmentStart int
+	)
+
+	endSegment := func() error {
+		value := token.String()[:keep]
+		if !haveKey {
+			key, value = value, ""
+		}
+		if key == "" && (haveKey || significant) {
+			return &SyntaxError{Offset: segmentStart, Msg: "missing option key"}
+		}
+		if key != "" {
+			mountOpts[key] = value
+		}
+
+		token.Reset()
+		keep, significant, key, haveKey = 0, false, "", false
+		return nil
+	}
+
+	for i := 0; i < len(optionString); {
+		c := optionString[i]
+		switch {
+		case c == ',':
+			if err := endSegment(); err != nil {
+				return mountOpts, err
+			}
+			i++
+			segmentStart = i
+
+		case !haveKey && separator != "" && strings.HasPrefix(optionString[i:], separator):
+			key = token.String()[:keep]
+			token.Reset()
+			keep, significant, haveKey = 0, false, true
+			i += len(separator)
+
+		case c == '\\':
+			if i+1 >= len(optionString) {
+				return mountOpts, &SyntaxError{Offset: i, Msg: "unterminated escape sequence"}
+			}
+			token.WriteByte(optionString[i+1])
+			keep, significant = token.Len(), true
+			i += 2
+
+		case c == '"':
+			end, err := readQuoted(optionString, i, &token)
+			if err != nil {
+				return mountOpts, err
+			}
+			keep, significant = token.Len(), true
+			i = end
+
+		case c == ' ' || c == '\t':
+			if significant {
+				token.WriteByte(c)
+			}
+			i++
+
+		default:
+			token.WriteByte(c)
+			keep, significant = token.Len(), true
+			i++
+		}
+	}
+
+	return mountOpts, endSegment()
+}
+
+// readQuoted writes the contents of the quoted string starting at start to
+// token and returns the offset just past the closing quote.
+func readQuoted(s string, start int, token *strings.Builder) (int, error) {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return 0, &SyntaxError{Offset: i, Msg: "unterminated escape sequence"}
+			}
+			i++
+		}
+		token.WriteByte(s[i])
+	}
+	return 0, &SyntaxError{Offset: start, Msg: "unterminated quoted value"}
+}
+
+// OptionMapToString is the inverse of ParseOptionStringToMap. Options are
+// emitted in key order, joined by commas, with key and value joined by
+// separator. Options with an empty or nil value are emitted as bare flags.
+// Values are emitted verbatim, as the kernel has no way of escaping them, so
+// it fails for options that would not parse back to themselves: empty keys,
+// keys containing a comma or separator, and values containing a comma.
+func OptionMapToString(opts map[string]interface{}, separator string) (string, error) {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	segments := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := optionValueToString(opts[k])
+		switch {
+		case k == "":
+			return "", fmt.Errorf("cannot represent option with an empty key")
+		case strings.Contains(k, ","), separator != "" && strings.Contains(k, separator):
+			return "", fmt.Errorf("cannot represent option %q: key contains a separator", k)
+		case strings.Contains(value, ","):
+			return "", fmt.Errorf("cannot represent option %q: value contains a comma", k)
+		}
+
+		if value == "" {
+			segments = append(segments, k)
+		} else {
+			segments = append(segments, k+separator+value)
+		}
+	}
+
+	return strings.Join(segments, ","), nil
+}
+
+func optionValueToString(value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+var optionValueEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `"`, `\"`)
+
+// EscapeOptionValue backslash-escapes commas, quotes and backslashes so that
+// the value cannot be split into further options.
+func EscapeOptionValue(value string) string {
+	return optionValueEscaper.Replace(value)
+}
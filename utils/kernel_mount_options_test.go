@@ -1,6 +1,11 @@
 package utils_test
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
 	"code.cloudfoundry.org/volume-mount-options/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -65,5 +70,170 @@ var _ = Describe("KernelMountOptions", func() {
 				}))
 			})
 		})
+
+		Context("given values with backslashes and quotes", func() {
+			BeforeEach(func() {
+				optionString = `username=DOMAIN\bob,password=a"b,uid=1000`
+			})
+
+			It("should keep them verbatim", func() {
+				Expect(opts).To(Equal(map[string]interface{}{
+					"username": `DOMAIN\bob`,
+					"password": `a"b`,
+					"uid":      "1000",
+				}))
+			})
+		})
 	})
+
+	Describe("#ParseOptionString", func() {
+		DescribeTable("with valid option strings",
+			func(optionString string, expected map[string]interface{}) {
+				opts, err := utils.ParseOptionString(optionString, "=")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(opts).To(Equal(expected))
+			},
+			Entry("plain options", "opt1=val1,opt2", map[string]interface{}{"opt1": "val1", "opt2": ""}),
+			Entry("a quoted value with a comma", `password="p,ss",uid=1000`, map[string]interface{}{"password": "p,ss", "uid": "1000"}),
+			Entry("an escaped comma", `lowerdir=/a\,/b`, map[string]interface{}{"lowerdir": "/a,/b"}),
+			Entry("an escaped quote inside quotes", `secret="a\"b"`, map[string]interface{}{"secret": `a"b`}),
+			Entry("a partially quoted value", `secret=abc"d,e"f`, map[string]interface{}{"secret": "abcd,ef"}),
+			Entry("surrounding whitespace", " opt1 = val1 , opt2 ", map[string]interface{}{"opt1": "val1", "opt2": ""}),
+			Entry("quoted whitespace", `opt1=" val1 "`, map[string]interface{}{"opt1": " val1 "}),
+			Entry("empty segments", ",opt1=val1,,opt2,", map[string]interface{}{"opt1": "val1", "opt2": ""}),
+			Entry("an escaped value round-tripped", "user="+utils.EscapeOptionValue(`bob,"x"\`), map[string]interface{}{"user": `bob,"x"\`}),
+		)
+
+		DescribeTable("with invalid option strings",
+			func(optionString string, offset int, msg string) {
+				opts, err := utils.ParseOptionString(optionString, "=")
+
+				var syntaxErr *utils.SyntaxError
+				Expect(errors.As(err, &syntaxErr)).To(BeTrue())
+				Expect(syntaxErr.Offset).To(Equal(offset))
+				Expect(syntaxErr.Msg).To(Equal(msg))
+				Expect(err).To(MatchError(fmt.Sprintf("%s at offset %d", msg, offset)))
+				Expect(opts).NotTo(BeNil())
+			},
+			Entry("an unterminated quote", `opt1=val1,opt2="val2`, 15, "unterminated quoted value"),
+			Entry("a trailing backslash", `opt1=val1\`, 9, "unterminated escape sequence"),
+			Entry("a missing key", `opt1=val1,=val2`, 10, "missing option key"),
+		)
+
+		It("should return the options parsed before a syntax error", func() {
+			opts, err := utils.ParseOptionString(`opt1=val1,opt2="val2`, "=")
+			Expect(err).To(HaveOccurred())
+			Expect(opts).To(Equal(map[string]interface{}{"opt1": "val1"}))
+		})
+	})
+
+	Describe("#OptionMapToString", func() {
+		var (
+			opts         map[string]interface{}
+			optionString string
+			err          error
+		)
+
+		JustBeforeEach(func() {
+			optionString, err = utils.OptionMapToString(opts, "=")
+		})
+
+		Context("given an empty map of options", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{}
+			})
+
+			It("should return an empty option string", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(optionString).To(BeEmpty())
+			})
+		})
+
+		Context("given a map of options", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{
+					"vers":    "4.1",
+					"actimeo": 30,
+					"nosuid":  "",
+					"nodev":   nil,
+					"ro":      true,
+				}
+			})
+
+			It("should return the options sorted by key with bare flags for empty values", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(optionString).To(Equal("actimeo=30,nodev,nosuid,ro=true,vers=4.1"))
+			})
+		})
+
+		DescribeTable("given options that cannot be represented",
+			func(options map[string]interface{}, message string) {
+				opts = options
+				optionString, err = utils.OptionMapToString(opts, "=")
+				Expect(err).To(MatchError(message))
+				Expect(optionString).To(BeEmpty())
+			},
+			Entry("a value with a comma", map[string]interface{}{"lowerdir": "/a,/b"}, `cannot represent option "lowerdir": value contains a comma`),
+			Entry("a key with a comma", map[string]interface{}{"a,b": ""}, `cannot represent option "a,b": key contains a separator`),
+			Entry("a key with the separator", map[string]interface{}{"a=b": "c"}, `cannot represent option "a=b": key contains a separator`),
+			Entry("an empty key", map[string]interface{}{"": "c"}, "cannot represent option with an empty key"),
+		)
+
+		It("should use the given separator", func() {
+			Expect(utils.OptionMapToString(map[string]interface{}{"a": "b", "c": "d"}, ":")).To(Equal("a:b,c:d"))
+		})
+
+		DescribeTable("round-tripping with ParseOptionStringToMap",
+			func(optionString string) {
+				Expect(utils.OptionMapToString(utils.ParseOptionStringToMap(optionString, "="), "=")).To(Equal(optionString))
+			},
+			Entry("empty", ""),
+			Entry("single option", "opt1=val1"),
+			Entry("bare flags", "nodev,nosuid"),
+			Entry("mixed", "nodev,opt1=val1,opt2=val2=val3"),
+			Entry("quotes, backslashes and spaces", `password= a"b\ ,username=DOMAIN\bob`),
+		)
+
+		It("should round-trip every representable map of options", func() {
+			alphabet := []rune{'a', 'b', '=', ',', '"', '\\', ' ', '\t'}
+			randomString := func(r *rand.Rand) string {
+				runes := make([]rune, r.Intn(5))
+				for i := range runes {
+					runes[i] = alphabet[r.Intn(len(alphabet))]
+				}
+				return string(runes)
+			}
+
+			r := rand.New(rand.NewSource(GinkgoRandomSeed()))
+			for i := 0; i < 1000; i++ {
+				opts := make(map[string]interface{})
+				representable := true
+				for j := r.Intn(4); j >= 0; j-- {
+					key, value := randomString(r), randomString(r)
+					opts[key] = value
+					if key == "" || strings.ContainsAny(key, ",=") || strings.Contains(value, ",") {
+						representable = false
+					}
+				}
+
+				optionString, err := utils.OptionMapToString(opts, "=")
+				if !representable {
+					Expect(err).To(HaveOccurred(), "%#v", opts)
+					continue
+				}
+				Expect(err).NotTo(HaveOccurred(), "%#v", opts)
+				Expect(utils.ParseOptionStringToMap(optionString, "=")).To(Equal(opts), "%q", optionString)
+			}
+		})
+	})
+
+	DescribeTable("#EscapeOptionValue",
+		func(value string, expected string) {
+			Expect(utils.EscapeOptionValue(value)).To(Equal(expected))
+		},
+		Entry("plain value", "bob", "bob"),
+		Entry("value with a comma", "bob,uid=0", `bob\,uid=0`),
+		Entry("value with a backslash", `DOMAIN\bob`, `DOMAIN\\bob`),
+		Entry("value with a quote", `say "hi"`, `say \"hi\"`),
+	)
 })
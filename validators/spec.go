@@ -0,0 +1,74 @@
+package validators
+
+import (
+	"fmt"
+	"regexp"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+)
+
+// Spec names a built-in validator and its arguments, so that validators can
+// be declared in mask documents. Keys restricts the validator to some
+// options, and Optional lets empty values pass.
+type Spec struct {
+	Name     string   `json:"name" yaml:"name"`
+	Keys     []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Min      *int64   `json:"min,omitempty" yaml:"min,omitempty"`
+	Max      *int64   `json:"max,omitempty" yaml:"max,omitempty"`
+	Pattern  string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Values   []string `json:"values,omitempty" yaml:"values,omitempty"`
+	Optional bool     `json:"optional,omitempty" yaml:"optional,omitempty"`
+}
+
+type specValidator struct {
+	vmo.UserOptsValidation
+	spec Spec
+}
+
+// Build returns the validator named by s. Validators built from a Spec can
+// be described again with SpecOf.
+func (s Spec) Build() (vmo.UserOptsValidation, error) {
+	var v vmo.UserOptsValidation
+	switch s.Name {
+	case "int-range":
+		if s.Min == nil || s.Max == nil {
+			return nil, fmt.Errorf("validator %s requires min and max", s.Name)
+		}
+		v = IntRange(*s.Min, *s.Max)
+	case "matches-regexp":
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("validator %s: %w", s.Name, err)
+		}
+		v = MatchesRegexp(re)
+	case "one-of":
+		if len(s.Values) == 0 {
+			return nil, fmt.Errorf("validator %s requires values", s.Name)
+		}
+		v = OneOf(s.Values...)
+	case "non-empty":
+		v = NonEmpty()
+	case "absolute-path":
+		v = AbsolutePath()
+	case "numeric-id":
+		v = NumericID()
+	default:
+		return nil, fmt.Errorf("unknown validator %q", s.Name)
+	}
+
+	if s.Optional {
+		v = Optional(v)
+	}
+	if len(s.Keys) > 0 {
+		v = ForKeys(v, s.Keys...)
+	}
+	return specValidator{UserOptsValidation: v, spec: s}, nil
+}
+
+// SpecOf returns the Spec that v was built from.
+func SpecOf(v vmo.UserOptsValidation) (Spec, bool) {
+	if s, ok := v.(specValidator); ok {
+		return s.spec, true
+	}
+	return Spec{}, false
+}
@@ -0,0 +1,47 @@
+package validators_test
+
+import (
+	"code.cloudfoundry.org/volume-mount-options/validators"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spec", func() {
+	int64p := func(n int64) *int64 { return &n }
+
+	DescribeTable("#Build",
+		func(spec validators.Spec, key, valid, invalid string) {
+			v, err := spec.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Validate(key, valid)).To(Succeed())
+			Expect(v.Validate(key, invalid)).NotTo(Succeed())
+
+			described, ok := validators.SpecOf(v)
+			Expect(ok).To(BeTrue())
+			Expect(described).To(Equal(spec))
+		},
+		Entry("int-range", validators.Spec{Name: "int-range", Min: int64p(1), Max: int64p(10)}, "retrans", "10", "11"),
+		Entry("matches-regexp", validators.Spec{Name: "matches-regexp", Pattern: `^krb5[ip]?$`}, "sec", "krb5i", "sys"),
+		Entry("one-of", validators.Spec{Name: "one-of", Values: []string{"3", "4.1"}}, "vers", "3", "4.2"),
+		Entry("non-empty", validators.Spec{Name: "non-empty"}, "username", "bob", ""),
+		Entry("absolute-path", validators.Spec{Name: "absolute-path"}, "source", "/export", "export"),
+		Entry("numeric-id", validators.Spec{Name: "numeric-id", Keys: []string{"uid"}}, "uid", "1000", "bob"),
+		Entry("optional", validators.Spec{Name: "numeric-id", Optional: true}, "uid", "", "bob"),
+	)
+
+	DescribeTable("#Build with invalid specs",
+		func(spec validators.Spec, message string) {
+			_, err := spec.Build()
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("an unknown name", validators.Spec{Name: "bogus"}, `unknown validator "bogus"`),
+		Entry("a missing bound", validators.Spec{Name: "int-range", Min: int64p(1)}, "validator int-range requires min and max"),
+		Entry("an invalid pattern", validators.Spec{Name: "matches-regexp", Pattern: "("}, "validator matches-regexp"),
+		Entry("no values", validators.Spec{Name: "one-of"}, "validator one-of requires values"),
+	)
+
+	It("should not describe other validators", func() {
+		_, ok := validators.SpecOf(validators.NonEmpty())
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,240 @@
+// Package validators provides ready-made UserOptsValidations and combinators
+// for use with NewMountOptsMask.
+package validators
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+)
+
+// validator fails with "<key>: must <desc>, got <value>" when check fails,
+// or with the error of fail when it is set. Keys outside of keys, when set,
+// always pass.
+type validator struct {
+	desc  string
+	keys  []string
+	check func(key, value string) bool
+	fail  func(key, value string) error
+}
+
+func (v validator) Validate(key, value string) error {
+	if !v.appliesTo(key) || v.check(key, value) {
+		return nil
+	}
+	if v.fail != nil {
+		return v.fail(key, value)
+	}
+	return fmt.Errorf("%s: must %s, got %q", key, v.desc, value)
+}
+
+func (v validator) appliesTo(key string) bool {
+	return v.keys == nil || contains(v.keys, key)
+}
+
+func asValidator(v vmo.UserOptsValidation) (validator, bool) {
+	if s, ok := v.(specValidator); ok {
+		v = s.UserOptsValidation
+	}
+	d, ok := v.(validator)
+	return d, ok
+}
+
+func describe(v vmo.UserOptsValidation) string {
+	if d, ok := asValidator(v); ok {
+		return d.desc
+	}
+	return "pass validation"
+}
+
+// keysOf returns the keys v is restricted to, or nil when v applies to all
+// keys.
+func keysOf(v vmo.UserOptsValidation) []string {
+	if d, ok := asValidator(v); ok {
+		return d.keys
+	}
+	return nil
+}
+
+func IntRange(min, max int64) vmo.UserOptsValidation {
+	return validator{
+		desc: fmt.Sprintf("be an integer between %d and %d", min, max),
+		check: func(_, value string) bool {
+			n, err := strconv.ParseInt(value, 10, 64)
+			return err == nil && n >= min && n <= max
+		},
+	}
+}
+
+func MatchesRegexp(re *regexp.Regexp) vmo.UserOptsValidation {
+	return validator{
+		desc: fmt.Sprintf("match %s", re.String()),
+		check: func(_, value string) bool {
+			return re.MatchString(value)
+		},
+	}
+}
+
+func OneOf(values ...string) vmo.UserOptsValidation {
+	return validator{
+		desc: fmt.Sprintf("be one of [%s]", strings.Join(values, ", ")),
+		check: func(_, value string) bool {
+			for _, v := range values {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func NonEmpty() vmo.UserOptsValidation {
+	return validator{
+		desc: "not be empty",
+		check: func(_, value string) bool {
+			return value != ""
+		},
+	}
+}
+
+func AbsolutePath() vmo.UserOptsValidation {
+	return validator{
+		desc: "be an absolute path",
+		check: func(_, value string) bool {
+			return path.IsAbs(value)
+		},
+	}
+}
+
+// NumericID accepts uids and gids: integers from 0 to 4294967294, excluding
+// the reserved (uid_t)-1.
+func NumericID() vmo.UserOptsValidation {
+	return validator{
+		desc: "be a numeric id",
+		check: func(_, value string) bool {
+			n, err := strconv.ParseUint(value, 10, 32)
+			return err == nil && n < 1<<32-1
+		},
+	}
+}
+
+// ForKeys applies v only to the given keys. Other keys pass, also when the
+// result is wrapped in Not or Optional.
+func ForKeys(v vmo.UserOptsValidation, keys ...string) vmo.UserOptsValidation {
+	restricted := []string{}
+	for _, k := range keys {
+		if inner := keysOf(v); inner == nil || contains(inner, k) {
+			restricted = append(restricted, k)
+		}
+	}
+
+	return validator{
+		desc: describe(v),
+		keys: restricted,
+		check: func(key, value string) bool {
+			return v.Validate(key, value) == nil
+		},
+		fail: v.Validate,
+	}
+}
+
+// Optional applies v only to non-empty values.
+func Optional(v vmo.UserOptsValidation) vmo.UserOptsValidation {
+	return validator{
+		desc: describe(v) + " or be empty",
+		keys: keysOf(v),
+		check: func(key, value string) bool {
+			return value == "" || v.Validate(key, value) == nil
+		},
+	}
+}
+
+// Not fails when v passes. Keys that v is not restricted to still pass.
+func Not(v vmo.UserOptsValidation) vmo.UserOptsValidation {
+	return validator{
+		desc: "not " + describe(v),
+		keys: keysOf(v),
+		check: func(key, value string) bool {
+			return v.Validate(key, value) != nil
+		},
+	}
+}
+
+// All fails with the error of the first validation that fails. Like Any, it
+// is restricted to the keys its validations are restricted to.
+func All(vs ...vmo.UserOptsValidation) vmo.UserOptsValidation {
+	descs := make([]string, len(vs))
+	for i, v := range vs {
+		descs[i] = describe(v)
+	}
+
+	first := func(key, value string) error {
+		for _, v := range vs {
+			if err := v.Validate(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return validator{
+		desc: strings.Join(descs, " and "),
+		keys: unionKeys(vs),
+		check: func(key, value string) bool {
+			return first(key, value) == nil
+		},
+		fail: first,
+	}
+}
+
+func Any(vs ...vmo.UserOptsValidation) vmo.UserOptsValidation {
+	descs := make([]string, len(vs))
+	for i, v := range vs {
+		descs[i] = describe(v)
+	}
+
+	return validator{
+		desc: strings.Join(descs, " or "),
+		keys: unionKeys(vs),
+		check: func(key, value string) bool {
+			for _, v := range vs {
+				if v.Validate(key, value) == nil {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// unionKeys returns the keys any of vs is restricted to, or nil when one of
+// them applies to all keys. Other keys pass every one of vs.
+func unionKeys(vs []vmo.UserOptsValidation) []string {
+	keys := []string{}
+	for _, v := range vs {
+		inner := keysOf(v)
+		if inner == nil {
+			return nil
+		}
+		for _, k := range inner {
+			if !contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, entry := range list {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package validators_test
+
+import (
+	"errors"
+	"regexp"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	"code.cloudfoundry.org/volume-mount-options/validators"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validators", func() {
+	DescribeTable("with valid values",
+		func(v vmo.UserOptsValidation, key, value string) {
+			Expect(v.Validate(key, value)).To(Succeed())
+		},
+		Entry("IntRange", validators.IntRange(1, 10), "retrans", "10"),
+		Entry("MatchesRegexp", validators.MatchesRegexp(regexp.MustCompile(`^krb5[ip]?$`)), "sec", "krb5i"),
+		Entry("OneOf", validators.OneOf("3", "4.1"), "vers", "3"),
+		Entry("NonEmpty", validators.NonEmpty(), "username", "bob"),
+		Entry("AbsolutePath", validators.AbsolutePath(), "source", "/export/share"),
+		Entry("NumericID", validators.NumericID(), "uid", "4294967294"),
+		Entry("ForKeys with another key", validators.ForKeys(validators.NumericID(), "uid", "gid"), "username", "bob"),
+		Entry("ForKeys with a listed key", validators.ForKeys(validators.NumericID(), "uid", "gid"), "gid", "1000"),
+		Entry("Optional with an empty value", validators.Optional(validators.NumericID()), "uid", ""),
+		Entry("Not", validators.Not(validators.OneOf("0")), "uid", "1000"),
+		Entry("All", validators.All(validators.NonEmpty(), validators.NumericID()), "uid", "1000"),
+		Entry("Any", validators.Any(validators.NumericID(), validators.OneOf("nobody")), "uid", "nobody"),
+		Entry("Not of ForKeys with another key", validators.Not(validators.ForKeys(validators.OneOf("0"), "uid")), "gid", "0"),
+		Entry("Optional of ForKeys with another key", validators.Optional(validators.ForKeys(validators.NumericID(), "uid")), "username", "bob"),
+		Entry("nested ForKeys with a key of the outer only", validators.ForKeys(validators.ForKeys(validators.NumericID(), "uid"), "uid", "gid"), "gid", "bob"),
+		Entry("Not of All", validators.Not(validators.All(validators.NonEmpty(), validators.OneOf("0"))), "uid", "1000"),
+		Entry("Not of All of ForKeys with another key", validators.Not(validators.All(validators.ForKeys(validators.IntRange(0, 10), "uid"))), "gid", "x"),
+		Entry("Not of Any of ForKeys with another key", validators.Not(validators.Any(validators.ForKeys(validators.OneOf("0"), "uid"), validators.ForKeys(validators.OneOf("0"), "gid"))), "username", "0"),
+		Entry("Not of a built spec with another key", validators.Not(mustBuild(validators.Spec{Name: "one-of", Values: []string{"0"}, Keys: []string{"uid"}})), "gid", "0"),
+	)
+
+	DescribeTable("with invalid values",
+		func(v vmo.UserOptsValidation, key, value, expected string) {
+			Expect(v.Validate(key, value)).To(MatchError(expected))
+		},
+		Entry("IntRange", validators.IntRange(1, 10), "retrans", "11", `retrans: must be an integer between 1 and 10, got "11"`),
+		Entry("IntRange with a non-integer", validators.IntRange(1, 10), "retrans", "banana", `retrans: must be an integer between 1 and 10, got "banana"`),
+		Entry("MatchesRegexp", validators.MatchesRegexp(regexp.MustCompile(`^krb5[ip]?$`)), "sec", "sys", `sec: must match ^krb5[ip]?$, got "sys"`),
+		Entry("OneOf", validators.OneOf("3", "4.1"), "vers", "2", `vers: must be one of [3, 4.1], got "2"`),
+		Entry("NonEmpty", validators.NonEmpty(), "username", "", `username: must not be empty, got ""`),
+		Entry("AbsolutePath", validators.AbsolutePath(), "source", "export", `source: must be an absolute path, got "export"`),
+		Entry("NumericID", validators.NumericID(), "uid", "-1", `uid: must be a numeric id, got "-1"`),
+		Entry("NumericID with the reserved id", validators.NumericID(), "uid", "4294967295", `uid: must be a numeric id, got "4294967295"`),
+		Entry("ForKeys", validators.ForKeys(validators.NumericID(), "uid", "gid"), "uid", "bob", `uid: must be a numeric id, got "bob"`),
+		Entry("Optional", validators.Optional(validators.NumericID()), "uid", "bob", `uid: must be a numeric id or be empty, got "bob"`),
+		Entry("Not", validators.Not(validators.OneOf("0")), "uid", "0", `uid: must not be one of [0], got "0"`),
+		Entry("All", validators.All(validators.NonEmpty(), validators.NumericID()), "uid", "", `uid: must not be empty, got ""`),
+		Entry("Any", validators.Any(validators.NumericID(), validators.OneOf("nobody")), "uid", "bob", `uid: must be a numeric id or be one of [nobody], got "bob"`),
+		Entry("Not of ForKeys", validators.Not(validators.ForKeys(validators.OneOf("0"), "uid")), "uid", "0", `uid: must not be one of [0], got "0"`),
+		Entry("Not of All", validators.Not(validators.All(validators.NonEmpty(), validators.OneOf("0"))), "uid", "0", `uid: must not not be empty and be one of [0], got "0"`),
+		Entry("Any of ForKeys", validators.Any(validators.ForKeys(validators.NumericID(), "uid"), validators.OneOf("nobody")), "uid", "bob", `uid: must be a numeric id or be one of [nobody], got "bob"`),
+		Entry("Optional of All", validators.Optional(validators.All(validators.NumericID(), validators.Not(validators.OneOf("0")))), "uid", "0", `uid: must be a numeric id and not be one of [0] or be empty, got "0"`),
+		Entry("Not of All of ForKeys", validators.Not(validators.All(validators.ForKeys(validators.IntRange(0, 10), "uid"))), "uid", "5", `uid: must not be an integer between 0 and 10, got "5"`),
+		Entry("Not of Any of ForKeys", validators.Not(validators.Any(validators.ForKeys(validators.OneOf("0"), "uid"), validators.ForKeys(validators.OneOf("0"), "gid"))), "gid", "0", `gid: must not be one of [0] or be one of [0], got "0"`),
+		Entry("Not of a built spec", validators.Not(mustBuild(validators.Spec{Name: "one-of", Values: []string{"0"}, Keys: []string{"uid"}})), "uid", "0", `uid: must not be one of [0], got "0"`),
+		Entry("Not with a custom validation", validators.Not(vmo.UserOptsValidationFunc(func(string, string) error { return nil })), "uid", "0", `uid: must not pass validation, got "0"`),
+	)
+
+	Context("when plugged into a mask", func() {
+		It("should validate the user options", func() {
+			mask, err := vmo.NewMountOptsMask(
+				[]string{"uid", "gid", "username"},
+				nil,
+				nil,
+				nil,
+				nil,
+				validators.ForKeys(validators.NumericID(), "uid", "gid"),
+				validators.ForKeys(validators.NonEmpty(), "username"),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = vmo.NewMountOpts(map[string]interface{}{"uid": "bob", "gid": 1000, "username": ""}, mask)
+			Expect(errors.Is(err, vmo.ErrValidationFailed)).To(BeTrue())
+			Expect(err).To(MatchError(`- validation mount options failed: uid: must be a numeric id, got "bob", username: must not be empty, got ""
+`))
+		})
+	})
+})
+
+func mustBuild(s validators.Spec) vmo.UserOptsValidation {
+	v, err := s.Build()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package volumemountoptionsfakes
+
+import (
+	"context"
+	"sync"
+
+	volume_mount_options "code.cloudfoundry.org/volume-mount-options"
+)
+
+type FakeMountOptsValidation struct {
+	ValidateMountOptsStub        func(context.Context, volume_mount_options.MountOpts, map[string]interface{}) error
+	validateMountOptsMutex       sync.RWMutex
+	validateMountOptsArgsForCall []struct {
+		arg1 context.Context
+		arg2 volume_mount_options.MountOpts
+		arg3 map[string]interface{}
+	}
+	validateMountOptsReturns struct {
+		result1 error
+	}
+	validateMountOptsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOpts(arg1 context.Context, arg2 volume_mount_options.MountOpts, arg3 map[string]interface{}) error {
+	fake.validateMountOptsMutex.Lock()
+	ret, specificReturn := fake.validateMountOptsReturnsOnCall[len(fake.validateMountOptsArgsForCall)]
+	fake.validateMountOptsArgsForCall = append(fake.validateMountOptsArgsForCall, struct {
+		arg1 context.Context
+		arg2 volume_mount_options.MountOpts
+		arg3 map[string]interface{}
+	}{arg1, arg2, arg3})
+	stub := fake.ValidateMountOptsStub
+	fakeReturns := fake.validateMountOptsReturns
+	fake.recordInvocation("ValidateMountOpts", []interface{}{arg1, arg2, arg3})
+	fake.validateMountOptsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOptsCallCount() int {
+	fake.validateMountOptsMutex.RLock()
+	defer fake.validateMountOptsMutex.RUnlock()
+	return len(fake.validateMountOptsArgsForCall)
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOptsCalls(stub func(context.Context, volume_mount_options.MountOpts, map[string]interface{}) error) {
+	fake.validateMountOptsMutex.Lock()
+	defer fake.validateMountOptsMutex.Unlock()
+	fake.ValidateMountOptsStub = stub
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOptsArgsForCall(i int) (context.Context, volume_mount_options.MountOpts, map[string]interface{}) {
+	fake.validateMountOptsMutex.RLock()
+	defer fake.validateMountOptsMutex.RUnlock()
+	argsForCall := fake.validateMountOptsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOptsReturns(result1 error) {
+	fake.validateMountOptsMutex.Lock()
+	defer fake.validateMountOptsMutex.Unlock()
+	fake.ValidateMountOptsStub = nil
+	fake.validateMountOptsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeMountOptsValidation) ValidateMountOptsReturnsOnCall(i int, result1 error) {
+	fake.validateMountOptsMutex.Lock()
+	defer fake.validateMountOptsMutex.Unlock()
+	fake.ValidateMountOptsStub = nil
+	if fake.validateMountOptsReturnsOnCall == nil {
+		fake.validateMountOptsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.validateMountOptsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeMountOptsValidation) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.validateMountOptsMutex.RLock()
+	defer fake.validateMountOptsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeMountOptsValidation) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ volume_mount_options.MountOptsValidation = new(FakeMountOptsValidation)
@@ -1,8 +1,10 @@
 package volume_mount_options
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -10,67 +12,80 @@ import (
 const ValidationFailErrorMessage = "- validation mount options failed: %s"
 const NotAllowedErrorMessage = "- Not allowed options: %s"
 const MissingOptionErrorMessage = "- Missing mandatory options: %s"
+const UnsafeOptionErrorMessage = "- Unsafe options: %s"
+const ConstraintErrorMessage = "- Option constraints violated: %s"
+const AliasConflictErrorMessage = "- Conflicting aliased options: %s"
+const ForbiddenOptionErrorMessage = "- Forbidden options: %s"
+const LockedOptionErrorMessage = "- Locked options: %s"
+const WrongScopeErrorMessage = "- Options not settable at this scope: %s"
 
 type MountOpts map[string]interface{}
 
+// NewMountOpts validates userOpts against mask and returns the options to
+// mount with. It compiles mask on every call, which costs several times as
+// much as the validation itself; drivers that validate many requests
+// against the same mask should Compile it once and use
+// CompiledMask.NewMountOpts instead.
 func NewMountOpts(userOpts map[string]interface{}, mask MountOptsMask) (MountOpts, error) {
-	mountOpts := make(map[string]interface{})
-	for k, v := range mask.Defaults {
-		mountOpts[k] = v
-	}
-
-	allowedErrorList := []string{}
-	for k, v := range userOpts {
-		var canonicalKey string
-		var ok bool
-		if canonicalKey, ok = mask.KeyPerms[k]; !ok {
-			canonicalKey = k
-		}
-
-		if inArray(mask.Ignored, canonicalKey) {
-			continue
-		}
+	return NewMountOptsWithContext(context.Background(), userOpts, mask)
+}
 
-		if inArray(mask.Allowed, canonicalKey) {
-			uv := uniformKeyData(canonicalKey, v)
-			mountOpts[canonicalKey] = uv
-		} else if !mask.SloppyMount {
-			allowedErrorList = append(allowedErrorList, k)
-		}
+func NewMountOptsWithContext(ctx context.Context, userOpts map[string]interface{}, mask MountOptsMask) (MountOpts, error) {
+	result, err := EvaluateMountOpts(ctx, userOpts, mask)
+	if err != nil {
+		return MountOpts{}, err
 	}
+	return result.Options, nil
+}
 
-	var validationErrorList []string
-	if mask.ValidationFunc != nil {
-		for key, val := range mountOpts {
-			for _, validationFunc := range mask.ValidationFunc {
-				err := validationFunc.Validate(key, fmt.Sprintf("%v", val))
-				if err != nil {
-					validationErrorList = append(validationErrorList, err.Error())
-				}
-			}
-		}
+// NewMountOptsForScope is like NewMountOpts, and also rejects options whose
+// scope in mask.Scopes is not scope.
+func NewMountOptsForScope(userOpts map[string]interface{}, mask MountOptsMask, scope OptionScope) (MountOpts, error) {
+	compiled, err := Compile(mask)
+	if err != nil {
+		return MountOpts{}, err
 	}
+	return compiled.NewMountOptsForScope(userOpts, scope)
+}
 
-	var mandatoryErrorList []string
-	for _, k := range mask.Mandatory {
-		if _, ok := mountOpts[k]; !ok {
-			mandatoryErrorList = append(mandatoryErrorList, k)
-		}
+// EvaluateMountOpts validates userOpts against mask like NewMountOpts, and
+// also reports what happened to each user option. The result is returned
+// even when validation fails, with empty Options. Like NewMountOpts, it
+// compiles mask on every call; see CompiledMask.Evaluate.
+func EvaluateMountOpts(ctx context.Context, userOpts map[string]interface{}, mask MountOptsMask) (*MountOptsResult, error) {
+	compiled, err := Compile(mask)
+	if err != nil {
+		return nil, err
 	}
+	return compiled.Evaluate(ctx, userOpts)
+}
 
-	errorString := buildErrorMessage(validationErrorList, ValidationFailErrorMessage)
-	errorString += buildErrorMessage(allowedErrorList, NotAllowedErrorMessage)
-	errorString += buildErrorMessage(mandatoryErrorList, MissingOptionErrorMessage)
+func optsValidationViolations(err error) []*Violation {
+	var mountOptsErr *MountOptsError
+	if errors.As(err, &mountOptsErr) {
+		return mountOptsErr.Violations
+	}
 
-	if hasErrors(allowedErrorList, validationErrorList, mandatoryErrorList) {
-		return MountOpts{}, errors.New(errorString)
+	var violation *Violation
+	if errors.As(err, &violation) {
+		return []*Violation{violation}
 	}
 
-	return mountOpts, nil
+	return []*Violation{{Kind: ErrValidationFailed, Cause: err}}
+}
+
+// Keys returns the option keys in sorted order.
+func (m MountOpts) Keys() []string {
+	return sortedKeys(m)
 }
 
-func hasErrors(allowedErrorList []string, validationErrorList []string, mandatoryErrorList []string) bool {
-	return len(allowedErrorList) > 0 || len(validationErrorList) > 0 || len(mandatoryErrorList) > 0
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func buildErrorMessage(validationErrorList []string, errorDesc string) string {
@@ -90,24 +105,19 @@ func inArray(list []string, key string) bool {
 	return false
 }
 
-func uniformKeyData(key string, data interface{}) string {
-	switch key {
-	case "auto-traverse-mounts":
-		return uniformData(data, true)
-
-	case "dircache":
-		return uniformData(data, true)
-
-	}
-
-	return uniformData(data, false)
-}
-
 func uniformData(data interface{}, boolAsInt bool) string {
 	switch t := data.(type) {
-	case int, int8, int16, int32, int64, float32, float64:
+	case int, int8, int16, int32, int64:
 		return fmt.Sprintf("%#v", data)
 
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32)
+
+	case float64:
+		// Decoded JSON numbers are float64; keep large integers out of
+		// exponent notation.
+		return strconv.FormatFloat(t, 'f', -1, 64)
+
 	case string:
 		return t
 
@@ -0,0 +1,81 @@
+package volume_mount_options_test
+
+import (
+	"testing"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+)
+
+func benchmarkMask(b *testing.B) vmo.MountOptsMask {
+	mask, err := vmo.NewMask(
+		vmo.WithAllowed("uid", "gid", "username", "password", "vers", "sec", "rsize", "wsize", "timeo", "retrans", "x-systemd.*"),
+		vmo.WithDefaults(map[string]interface{}{"vers": "4.1", "timeo": 600}),
+		vmo.WithAlias("UID", "uid"),
+		vmo.WithAlias("GID", "gid"),
+		vmo.WithIgnored("readonly", "mount"),
+		vmo.WithMandatory("uid", "gid"),
+		vmo.WithForbidden("suid", ""),
+		vmo.WithType("rsize", vmo.SizeType()),
+		vmo.WithType("wsize", vmo.SizeType()),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return mask
+}
+
+var benchmarkUserOpts = map[string]interface{}{
+	"UID":                 1000,
+	"gid":                 "1000",
+	"username":            "bob",
+	"password":            "secret",
+	"sec":                 "krb5p",
+	"rsize":               "1M",
+	"wsize":               "1M",
+	"readonly":            true,
+	"x-systemd.automount": "",
+}
+
+func BenchmarkNewMountOpts(b *testing.B) {
+	mask := benchmarkMask(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vmo.NewMountOpts(benchmarkUserOpts, mask); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompiledMaskNewMountOpts(b *testing.B) {
+	compiled, err := vmo.Compile(benchmarkMask(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := compiled.NewMountOpts(benchmarkUserOpts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompiledMaskNewMountOptsParallel(b *testing.B) {
+	compiled, err := vmo.Compile(benchmarkMask(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := compiled.NewMountOpts(benchmarkUserOpts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
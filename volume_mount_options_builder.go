@@ -0,0 +1,205 @@
+package volume_mount_options
+
+import (
+	"fmt"
+	"strconv"
+
+	"code.cloudfoundry.org/volume-mount-options/utils"
+)
+
+// MaskOption configures a MountOptsMask built by NewMask.
+type MaskOption func(*MountOptsMask) error
+
+// NewMask builds a MountOptsMask from options, applied in order, and lints
+// the result like NewMountOptsMask.
+func NewMask(options ...MaskOption) (MountOptsMask, error) {
+	mask := MountOptsMask{Defaults: make(map[string]interface{})}
+	for _, option := range options {
+		if err := option(&mask); err != nil {
+			return MountOptsMask{}, err
+		}
+	}
+
+	if diagnostics := LintMountOptsMask(mask); diagnostics.HasErrors() {
+		return MountOptsMask{}, &MaskError{Diagnostics: diagnostics}
+	}
+
+	return mask, nil
+}
+
+func WithAllowed(keys ...string) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.Allowed = append(m.Allowed, keys...)
+		return nil
+	}
+}
+
+// WithDefaults adds default values. A "sloppy_mount" default also sets
+// SloppyMount, as it does for NewMountOptsMask.
+func WithDefaults(defaults map[string]interface{}) MaskOption {
+	return func(m *MountOptsMask) error {
+		for k, v := range defaults {
+			m.Defaults[k] = v
+		}
+
+		if v, ok := defaults["sloppy_mount"]; ok {
+			sloppy, err := strconv.ParseBool(utils.InterfaceToString(v))
+			if err != nil {
+				return fmt.Errorf("invalid sloppy_mount option: %w", err)
+			}
+			m.SloppyMount = sloppy
+		}
+		return nil
+	}
+}
+
+func WithDefault(key string, value interface{}) MaskOption {
+	return WithDefaults(map[string]interface{}{key: value})
+}
+
+func WithKeyPerms(keyPerms map[string]string) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.KeyPerms == nil {
+			m.KeyPerms = make(map[string]string)
+		}
+		for alias, canonicalKey := range keyPerms {
+			m.KeyPerms[alias] = canonicalKey
+		}
+		return nil
+	}
+}
+
+func WithAlias(alias, canonicalKey string) MaskOption {
+	return WithKeyPerms(map[string]string{alias: canonicalKey})
+}
+
+func WithIgnored(keys ...string) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.Ignored = append(m.Ignored, keys...)
+		return nil
+	}
+}
+
+func WithMandatory(keys ...string) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.Mandatory = append(m.Mandatory, keys...)
+		return nil
+	}
+}
+
+func WithForbidden(key, reason string) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.Forbidden == nil {
+			m.Forbidden = make(map[string]string)
+		}
+		m.Forbidden[key] = reason
+		return nil
+	}
+}
+
+func WithLocked(key string, value interface{}) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.Locked == nil {
+			m.Locked = make(map[string]interface{})
+		}
+		m.Locked[key] = value
+		return nil
+	}
+}
+
+func WithScope(scope OptionScope, keys ...string) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.Scopes == nil {
+			m.Scopes = make(map[string]OptionScope)
+		}
+		for _, k := range keys {
+			m.Scopes[k] = scope
+		}
+		return nil
+	}
+}
+
+func WithLockedPolicy(policy LockedPolicy) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.LockedPolicy = policy
+		return nil
+	}
+}
+
+func WithSloppy(sloppy bool) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.SloppyMount = sloppy
+		return nil
+	}
+}
+
+func WithValidator(validations ...UserOptsValidation) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.ValidationFunc = append(m.ValidationFunc, validations...)
+		return nil
+	}
+}
+
+func WithMountOptsValidator(validations ...MountOptsValidation) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.OptsValidationFunc = append(m.OptsValidationFunc, validations...)
+		return nil
+	}
+}
+
+func WithType(key string, t OptionType) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.Types == nil {
+			m.Types = make(map[string]OptionType)
+		}
+		m.Types[key] = t
+		return nil
+	}
+}
+
+// WithNormalizer sets the normalizer of key. The first call replaces the
+// DefaultNormalizers profile; include DefaultNormalizers with
+// WithNormalizers to extend it instead.
+func WithNormalizer(key string, n Normalizer) MaskOption {
+	return WithNormalizers(map[string]Normalizer{key: n})
+}
+
+func WithNormalizers(normalizers map[string]Normalizer) MaskOption {
+	return func(m *MountOptsMask) error {
+		if m.Normalizers == nil {
+			m.Normalizers = make(map[string]Normalizer)
+		}
+		for k, n := range normalizers {
+			m.Normalizers[k] = n
+		}
+		return nil
+	}
+}
+
+func WithConstraint(constraints ...Constraint) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.Constraints = append(m.Constraints, constraints...)
+		return nil
+	}
+}
+
+func WithAliasPrecedence(precedence AliasPrecedence) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.AliasPrecedence = precedence
+		return nil
+	}
+}
+
+func WithUnknownOptions(policy UnknownOptionPolicy) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.UnknownOptions = policy
+		return nil
+	}
+}
+
+func WithUnsafeValues(policy UnsafeValuePolicy) MaskOption {
+	return func(m *MountOptsMask) error {
+		m.UnsafeValues = policy
+		return nil
+	}
+}
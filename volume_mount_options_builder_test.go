@@ -0,0 +1,107 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("#NewMask", func() {
+	It("should build the same mask as NewMountOptsMask", func() {
+		validation := vmo.UserOptsValidationFunc(func(string, string) error { return nil })
+
+		expected, err := vmo.NewMountOptsMask(
+			[]string{"uid", "gid"},
+			map[string]interface{}{"sloppy_mount": "true", "vers": "3"},
+			map[string]string{"UID": "uid"},
+			[]string{"readonly"},
+			[]string{"uid"},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		mask, err := vmo.NewMask(
+			vmo.WithAllowed("uid", "gid"),
+			vmo.WithDefault("sloppy_mount", "true"),
+			vmo.WithDefault("vers", "3"),
+			vmo.WithAlias("UID", "uid"),
+			vmo.WithIgnored("readonly"),
+			vmo.WithMandatory("uid"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mask).To(Equal(expected))
+		Expect(mask.SloppyMount).To(BeTrue())
+
+		mask, err = vmo.NewMask(vmo.WithValidator(validation))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mask.ValidationFunc).To(HaveLen(1))
+	})
+
+	It("should set every mask feature", func() {
+		optsValidation := vmo.MountOptsValidationFunc(func(context.Context, vmo.MountOpts, map[string]interface{}) error {
+			return nil
+		})
+
+		mask, err := vmo.NewMask(
+			vmo.WithAllowed("uid", "sec"),
+			vmo.WithSloppy(true),
+			vmo.WithForbidden("suid", "not on shared cells"),
+			vmo.WithLocked("nodev", true),
+			vmo.WithLockedPolicy(vmo.RejectLockedOverride),
+			vmo.WithScope(vmo.ScopeInstance, "sec"),
+			vmo.WithType("uid", vmo.IntType()),
+			vmo.WithNormalizer("sec", vmo.Lowercase()),
+			vmo.WithConstraint(vmo.Requires(vmo.Present("gid"), vmo.Present("uid"))),
+			vmo.WithAliasPrecedence(vmo.RejectAliasConflict),
+			vmo.WithUnknownOptions(vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}),
+			vmo.WithUnsafeValues(vmo.AllowUnsafeValues),
+			vmo.WithMountOptsValidator(optsValidation),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mask.SloppyMount).To(BeTrue())
+		Expect(mask.Forbidden).To(Equal(map[string]string{"suid": "not on shared cells"}))
+		Expect(mask.Locked).To(Equal(map[string]interface{}{"nodev": true}))
+		Expect(mask.LockedPolicy).To(Equal(vmo.RejectLockedOverride))
+		Expect(mask.Scopes).To(Equal(map[string]vmo.OptionScope{"sec": vmo.ScopeInstance}))
+		Expect(mask.Types).To(Equal(map[string]vmo.OptionType{"uid": vmo.IntType()}))
+		Expect(mask.Normalizers).To(HaveKey("sec"))
+		Expect(mask.Normalizers).NotTo(HaveKey("dircache"))
+		Expect(mask.Constraints).To(HaveLen(1))
+		Expect(mask.AliasPrecedence).To(Equal(vmo.RejectAliasConflict))
+		Expect(mask.UnknownOptions.Prefix).To(Equal("x-"))
+		Expect(mask.UnsafeValues).To(Equal(vmo.AllowUnsafeValues))
+		Expect(mask.OptsValidationFunc).To(HaveLen(1))
+	})
+
+	It("should not modify the maps passed to it", func() {
+		keyPerms := map[string]string{"UID": "uid"}
+		defaults := map[string]interface{}{"vers": "3"}
+
+		_, err := vmo.NewMask(
+			vmo.WithAllowed("uid", "gid", "vers"),
+			vmo.WithKeyPerms(keyPerms),
+			vmo.WithAlias("GID", "gid"),
+			vmo.WithDefaults(defaults),
+			vmo.WithDefault("uid", "1000"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyPerms).To(Equal(map[string]string{"UID": "uid"}))
+		Expect(defaults).To(Equal(map[string]interface{}{"vers": "3"}))
+	})
+
+	It("should return the error of an invalid sloppy_mount default", func() {
+		_, err := vmo.NewMask(vmo.WithDefault("sloppy_mount", "invalid"))
+		Expect(err).To(MatchError(`invalid sloppy_mount option: strconv.ParseBool: parsing "invalid": invalid syntax`))
+	})
+
+	It("should lint the mask", func() {
+		_, err := vmo.NewMask(vmo.WithAllowed("suid"), vmo.WithMandatory("suid"), vmo.WithForbidden("suid", ""))
+
+		var maskErr *vmo.MaskError
+		Expect(errors.As(err, &maskErr)).To(BeTrue())
+		Expect(err).To(MatchError("suid is both Mandatory and Forbidden"))
+	})
+})
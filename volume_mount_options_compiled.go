@@ -0,0 +1,351 @@
+package volume_mount_options
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// CompiledMask is an immutable, pre-compiled form of a MountOptsMask for
+// validating many sets of options. It is safe for concurrent use as long as
+// the mask's validators are.
+type CompiledMask struct {
+	mask        MountOptsMask
+	allowed     keySet
+	ignored     keySet
+	mandatory   map[string]keySet
+	forbidden   forbiddenSet
+	normalizers map[string]Normalizer
+	defaults    map[string]interface{}
+	locked      map[string]interface{}
+}
+
+// Compile copies mask and compiles its patterns. It fails if a pattern is
+// invalid.
+func Compile(mask MountOptsMask) (*CompiledMask, error) {
+	c := &CompiledMask{mask: copyMask(mask)}
+
+	var err error
+	if c.allowed, err = compileKeySet(c.mask.Allowed); err != nil {
+		return nil, err
+	}
+	if c.ignored, err = compileKeySet(c.mask.Ignored); err != nil {
+		return nil, err
+	}
+
+	c.mandatory = make(map[string]keySet, len(c.mask.Mandatory))
+	for _, entry := range c.mask.Mandatory {
+		if c.mandatory[entry], err = compileKeySet([]string{entry}); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.forbidden, err = compileForbidden(c.mask.Forbidden); err != nil {
+		return nil, err
+	}
+
+	c.normalizers = c.mask.Normalizers
+	if c.normalizers == nil {
+		c.normalizers = DefaultNormalizers()
+	}
+
+	// Typed defaults and locked values are coerced once, like user values.
+	if c.defaults, err = c.coerceStatic("default", c.mask.Defaults); err != nil {
+		return nil, err
+	}
+	if c.locked, err = c.coerceStatic("locked value", c.mask.Locked); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *CompiledMask) NewMountOpts(userOpts map[string]interface{}) (MountOpts, error) {
+	result, err := c.Evaluate(context.Background(), userOpts)
+	if err != nil {
+		return MountOpts{}, err
+	}
+	return result.Options, nil
+}
+
+func (c *CompiledMask) NewMountOptsForScope(userOpts map[string]interface{}, scope OptionScope) (MountOpts, error) {
+	result, err := c.EvaluateForScope(context.Background(), userOpts, scope)
+	if err != nil {
+		return MountOpts{}, err
+	}
+	return result.Options, nil
+}
+
+// Evaluate is the compiled equivalent of EvaluateMountOpts.
+func (c *CompiledMask) Evaluate(ctx context.Context, userOpts map[string]interface{}) (*MountOptsResult, error) {
+	return c.EvaluateForScope(ctx, userOpts, ScopeAny)
+}
+
+// EvaluateForScope is like Evaluate, and also rejects options whose scope is
+// not scope.
+func (c *CompiledMask) EvaluateForScope(ctx context.Context, userOpts map[string]interface{}, scope OptionScope) (*MountOptsResult, error) {
+	result := &MountOptsResult{Provenance: make(map[string]Provenance)}
+	mountOpts := make(map[string]interface{})
+	for k, v := range c.mask.Defaults {
+		mountOpts[k] = c.defaults[k]
+		result.Provenance[k] = Provenance{Source: SourceDefault, Key: k, RawValue: v}
+	}
+
+	var violations []*Violation
+	setBy := make(map[string]string)
+	inputs := make(map[string][]string)
+	conflicts := make(map[string]*Violation)
+	for _, k := range sortedKeys(userOpts) {
+		v := userOpts[k]
+		var canonicalKey string
+		var ok bool
+		if canonicalKey, ok = c.mask.KeyPerms[k]; !ok {
+			canonicalKey = k
+		}
+
+		if err := checkKey(k); err != nil {
+			violations = append(violations, &Violation{Kind: ErrUnsafeOption, Key: k, Value: v, Cause: err})
+			continue
+		}
+
+		if reason, ok := c.forbidden.lookup(k, canonicalKey); ok {
+			violation := &Violation{Kind: ErrForbiddenOption, Key: k, Value: v}
+			if reason != "" {
+				violation.Cause = errors.New(reason)
+			}
+			violations = append(violations, violation)
+			continue
+		}
+
+		if violation := c.checkScope(k, canonicalKey, v, scope); violation != nil {
+			violations = append(violations, violation)
+			continue
+		}
+
+		if locked, ok := c.mask.Locked[canonicalKey]; ok {
+			if !c.sameValue(canonicalKey, v, locked) {
+				if c.mask.LockedPolicy == RejectLockedOverride {
+					violations = append(violations, &Violation{Kind: ErrLockedOption, Key: k, Value: v})
+				} else {
+					result.warnf("option %s is locked and was overridden", k)
+				}
+			}
+			continue
+		}
+
+		// An ignored alias stays ignored, so that an alias added by another
+		// layer cannot revive it.
+		allowed := c.allowed.match(canonicalKey)
+		if ignored := max(c.ignored.match(canonicalKey), c.ignored.match(k)); ignored != noMatch && ignored >= allowed {
+			result.Ignored = append(result.Ignored, k)
+			continue
+		}
+
+		if allowed == noMatch {
+			switch c.mask.unknownOptionMode(canonicalKey) {
+			case DropUnknownOptions:
+				result.Dropped = append(result.Dropped, k)
+				continue
+			case DropUnknownOptionsWithWarning:
+				result.Dropped = append(result.Dropped, k)
+				result.warnf("option %s is not allowed and was dropped", k)
+				continue
+			case PassThroughUnknownOptions:
+				result.PassedThrough = append(result.PassedThrough, k)
+			default:
+				violations = append(violations, &Violation{Kind: ErrNotAllowed, Key: k, Value: v})
+				continue
+			}
+		}
+
+		uv, err := c.coerce(canonicalKey, v)
+		if err != nil {
+			violations = append(violations, &Violation{
+				Kind:  ErrValidationFailed,
+				Key:   k,
+				Value: v,
+				Cause: fmt.Errorf("%s: %w", k, err),
+			})
+			continue
+		}
+
+		uv, err = c.mask.UnsafeValues.sanitize(uv)
+		if err != nil {
+			violations = append(violations, &Violation{Kind: ErrUnsafeOption, Key: k, Value: v, Cause: err})
+			continue
+		}
+
+		if conflict, ok := conflicts[canonicalKey]; ok {
+			conflict.Related = append(conflict.Related, k)
+			continue
+		}
+		if prev, ok := setBy[canonicalKey]; ok && mountOpts[canonicalKey] != uv {
+			if c.mask.AliasPrecedence == RejectAliasConflict {
+				conflicts[canonicalKey] = &Violation{
+					Kind:    ErrAliasConflict,
+					Key:     canonicalKey,
+					Value:   v,
+					Related: append(append([]string(nil), inputs[canonicalKey]...), k),
+				}
+				violations = append(violations, conflicts[canonicalKey])
+				continue
+			}
+			if !c.mask.AliasPrecedence.prefers(k, prev, canonicalKey) {
+				result.warnf("option %s was overridden by %s", k, prev)
+				continue
+			}
+			result.warnf("option %s was overridden by %s", prev, k)
+		}
+		setBy[canonicalKey] = k
+		inputs[canonicalKey] = append(inputs[canonicalKey], k)
+		mountOpts[canonicalKey] = uv
+		result.Provenance[canonicalKey] = Provenance{Source: SourceUser, Key: k, RawValue: v}
+	}
+
+	// Only the alias that supplied the final value was rewritten.
+	for _, k := range sortedKeys(userOpts) {
+		if canonicalKey, ok := c.mask.KeyPerms[k]; ok && canonicalKey != k && setBy[canonicalKey] == k {
+			result.Aliases = append(result.Aliases, AliasRewrite{Alias: k, Canonical: canonicalKey})
+		}
+	}
+
+	for k, v := range c.mask.Locked {
+		mountOpts[k] = c.locked[k]
+		result.Provenance[k] = Provenance{Source: SourceLocked, Key: k, RawValue: v}
+	}
+
+	if c.mask.ValidationFunc != nil {
+		for _, key := range sortedKeys(mountOpts) {
+			val := mountOpts[key]
+			for _, validationFunc := range c.mask.ValidationFunc {
+				err := validationFunc.Validate(key, fmt.Sprintf("%v", val))
+				if err != nil {
+					violations = append(violations, &Violation{
+						Kind:  ErrValidationFailed,
+						Key:   key,
+						Value: val,
+						Cause: err,
+					})
+				}
+			}
+		}
+	}
+
+	for _, constraint := range c.mask.Constraints {
+		if violation := constraint.check(mountOpts); violation != nil {
+			violations = append(violations, violation)
+		}
+	}
+
+	for _, validation := range c.mask.OptsValidationFunc {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := validation.ValidateMountOpts(ctx, mountOpts, userOpts); err != nil {
+			violations = append(violations, optsValidationViolations(err)...)
+		}
+	}
+
+	for _, k := range c.mask.Mandatory {
+		if !c.mandatory[k].satisfiedBy(mountOpts) {
+			violations = append(violations, &Violation{Kind: ErrMissingOption, Key: k})
+		}
+	}
+
+	if len(violations) > 0 {
+		sortViolations(violations)
+		result.Options = MountOpts{}
+		result.Provenance = map[string]Provenance{}
+		return result, &MountOptsError{Violations: violations}
+	}
+
+	result.Options = mountOpts
+	return result, nil
+}
+
+func (c *CompiledMask) checkScope(key, canonicalKey string, value interface{}, scope OptionScope) *Violation {
+	want, ok := c.mask.Scopes[canonicalKey]
+	if scope == ScopeAny || !ok || want == ScopeAny || want == scope {
+		return nil
+	}
+	return &Violation{Kind: ErrWrongScope, Key: key, Value: value, Cause: fmt.Errorf("%s only", want)}
+}
+
+func (c *CompiledMask) coerce(key string, value interface{}) (string, error) {
+	return coerceValue(c.normalizers, c.mask.Types, key, value)
+}
+
+// coerceStatic coerces the typed values of a mask map.
+func (c *CompiledMask) coerceStatic(what string, values map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(values))
+	for _, k := range sortedKeys(values) {
+		coerced[k] = values[k]
+		if _, ok := c.mask.Types[k]; !ok {
+			continue
+		}
+		v, err := c.coerce(k, values[k])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s for %s: %w", what, k, err)
+		}
+		coerced[k] = v
+	}
+	return coerced, nil
+}
+
+func (c *CompiledMask) sameValue(key string, a, b interface{}) bool {
+	na, errA := c.coerce(key, a)
+	nb, errB := c.coerce(key, b)
+	return errA == nil && errB == nil && na == nb
+}
+
+// coerceValue normalizes value and coerces it to the type of key, if any.
+func coerceValue(normalizers map[string]Normalizer, types map[string]OptionType, key string, value interface{}) (string, error) {
+	v := uniformData(value, false)
+	n, normalized := normalizers[key]
+	if normalized {
+		var err error
+		if v, err = n(value); err != nil {
+			return "", err
+		}
+	}
+	t, ok := types[key]
+	if !ok {
+		return v, nil
+	}
+
+	coerced, err := t.Coerce(v)
+	if err != nil || t.Kind != OptionKindBool || !normalized {
+		return coerced, err
+	}
+	// Booleans are rendered by the option's normalizer.
+	b, _ := strconv.ParseBool(coerced)
+	return n(b)
+}
+
+func copyMask(mask MountOptsMask) MountOptsMask {
+	mask.Allowed = append([]string(nil), mask.Allowed...)
+	mask.Ignored = append([]string(nil), mask.Ignored...)
+	mask.Mandatory = append([]string(nil), mask.Mandatory...)
+	mask.ValidationFunc = append([]UserOptsValidation(nil), mask.ValidationFunc...)
+	mask.OptsValidationFunc = append([]MountOptsValidation(nil), mask.OptsValidationFunc...)
+	mask.Constraints = append([]Constraint(nil), mask.Constraints...)
+	mask.Defaults = copyMap(mask.Defaults)
+	mask.Locked = copyMap(mask.Locked)
+	mask.KeyPerms = copyMap(mask.KeyPerms)
+	mask.Forbidden = copyMap(mask.Forbidden)
+	mask.Types = copyMap(mask.Types)
+	mask.Scopes = copyMap(mask.Scopes)
+	if mask.Normalizers != nil {
+		mask.Normalizers = copyMap(mask.Normalizers)
+	}
+	return mask
+}
+
+func copyMap[V any](m map[string]V) map[string]V {
+	c := make(map[string]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompiledMask", func() {
+	var (
+		mask     vmo.MountOptsMask
+		compiled *vmo.CompiledMask
+	)
+
+	BeforeEach(func() {
+		var err error
+		mask, err = vmo.NewMask(
+			vmo.WithAllowed("uid", "gid", "x-systemd.*"),
+			vmo.WithDefault("vers", "3"),
+			vmo.WithAlias("UID", "uid"),
+			vmo.WithIgnored("readonly"),
+			vmo.WithMandatory("uid"),
+			vmo.WithForbidden("su*", "no setuid"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		compiled, err = vmo.Compile(mask)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("should behave like the mask it was compiled from",
+		func(userInput map[string]interface{}) {
+			expected, expectedErr := vmo.NewMountOpts(userInput, mask)
+			actual, actualErr := compiled.NewMountOpts(userInput)
+
+			Expect(actual).To(Equal(expected))
+			if expectedErr == nil {
+				Expect(actualErr).NotTo(HaveOccurred())
+			} else {
+				Expect(actualErr).To(MatchError(expectedErr.Error()))
+			}
+		},
+		Entry("valid options", map[string]interface{}{"UID": 1000, "gid": 1000, "x-systemd.automount": "", "readonly": true}),
+		Entry("missing options", map[string]interface{}{"gid": 1000}),
+		Entry("not allowed options", map[string]interface{}{"uid": 1000, "bogus": "x"}),
+		Entry("forbidden options", map[string]interface{}{"uid": 1000, "suid": ""}),
+	)
+
+	It("should not be affected by later changes to the mask", func() {
+		mask.Allowed[0] = "username"
+		mask.Defaults["vers"] = "4.1"
+
+		opts, err := compiled.NewMountOpts(map[string]interface{}{"uid": "1000"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(vmo.MountOpts{"uid": "1000", "vers": "3"}))
+	})
+
+	It("should be safe for concurrent use", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				result, err := compiled.Evaluate(context.Background(), map[string]interface{}{"UID": i})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Options).To(HaveKeyWithValue("uid", fmt.Sprintf("%d", i)))
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	It("should fail to compile invalid patterns", func() {
+		_, err := vmo.Compile(vmo.MountOptsMask{Allowed: []string{"x-["}})
+		Expect(err).To(MatchError(`invalid pattern "x-[": syntax error in pattern`))
+
+		_, err = vmo.Compile(vmo.MountOptsMask{Forbidden: map[string]string{"^(": ""}})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,266 @@
+package volume_mount_options
+
+import (
+	"reflect"
+
+	"code.cloudfoundry.org/volume-mount-options/utils"
+)
+
+// ComposeMasks combines layers, ordered from the broadest (e.g. platform) to
+// the narrowest (e.g. binding), into a single mask that can only be as
+// permissive as each layer:
+//
+//   - Allowed is the intersection of the layers that list Allowed options;
+//     layers with no Allowed options inherit them. Patterns are kept only
+//     when every such layer lists the same pattern. A layer cannot allow
+//     options that earlier layers without Allowed options do not pass
+//     through.
+//   - Ignored, Mandatory, Forbidden, Locked, KeyPerms, Scopes, Types,
+//     Constraints and validators accumulate. Locked values, aliases, scopes
+//     and types cannot be redefined by a later layer.
+//   - Defaults and Normalizers of later layers override earlier ones; a
+//     layer without Normalizers inherits those of earlier layers. Later layers
+//     cannot add Defaults or Locked values for options that are not Allowed,
+//     unless an earlier layer already set a value for them.
+//   - SloppyMount, LockedPolicy, UnsafeValues, AliasPrecedence and
+//     UnknownOptions take the strictest value of any layer.
+//
+// The result is linted like NewMountOptsMask.
+func ComposeMasks(layers ...MountOptsMask) (MountOptsMask, error) {
+	l := &linter{}
+	composed := MountOptsMask{Defaults: make(map[string]interface{})}
+	if len(layers) > 0 {
+		composed.SloppyMount = true
+		composed.UnsafeValues = AllowUnsafeValues
+	}
+
+	restricted := false
+	var introduced []staticValue
+	for i, layer := range layers {
+		if len(layer.Allowed) > 0 {
+			if restricted {
+				composed.Allowed = intersectAllowed(composed.Allowed, layer.Allowed)
+			} else {
+				for _, k := range layer.Allowed {
+					if !passedThrough(layers[:i], k) {
+						l.errorf("allowed-widens", k, "%s is Allowed by a later layer but not by an earlier one", k)
+					}
+				}
+				composed.Allowed = append([]string(nil), layer.Allowed...)
+				restricted = true
+			}
+		}
+		composed.Ignored = appendMissing(composed.Ignored, layer.Ignored)
+		composed.Mandatory = appendMissing(composed.Mandatory, layer.Mandatory)
+		composed.Constraints = append(composed.Constraints, layer.Constraints...)
+		composed.ValidationFunc = append(composed.ValidationFunc, layer.ValidationFunc...)
+		composed.OptsValidationFunc = append(composed.OptsValidationFunc, layer.OptsValidationFunc...)
+
+		for _, k := range sortedKeys(layer.Defaults) {
+			if i > 0 && !composed.hasStaticValue(k) {
+				introduced = append(introduced, staticValue{key: k, kind: "default"})
+			}
+			composed.Defaults[k] = layer.Defaults[k]
+		}
+
+		for _, k := range sortedKeys(layer.Forbidden) {
+			if composed.Forbidden == nil {
+				composed.Forbidden = make(map[string]string)
+			}
+			if _, ok := composed.Forbidden[k]; !ok {
+				composed.Forbidden[k] = layer.Forbidden[k]
+			}
+		}
+
+		for _, k := range sortedKeys(layer.Locked) {
+			if composed.Locked == nil {
+				composed.Locked = make(map[string]interface{})
+			}
+			if current, ok := composed.Locked[k]; ok {
+				if utils.InterfaceToString(current) != utils.InterfaceToString(layer.Locked[k]) {
+					l.errorf("locked-conflict", k, "%s is Locked to different values", k)
+				}
+				continue
+			}
+			if i > 0 && !composed.hasStaticValue(k) {
+				introduced = append(introduced, staticValue{key: k, kind: "locked"})
+			}
+			composed.Locked[k] = layer.Locked[k]
+		}
+
+		for _, alias := range sortedKeys(layer.KeyPerms) {
+			if composed.KeyPerms == nil {
+				composed.KeyPerms = make(map[string]string)
+			}
+			if current, ok := composed.KeyPerms[alias]; ok {
+				if current != layer.KeyPerms[alias] {
+					l.errorf("alias-conflict", alias, "alias %s refers to both %s and %s", alias, current, layer.KeyPerms[alias])
+				}
+				continue
+			}
+			composed.KeyPerms[alias] = layer.KeyPerms[alias]
+		}
+
+		for _, k := range sortedKeys(layer.Scopes) {
+			if composed.Scopes == nil {
+				composed.Scopes = make(map[string]OptionScope)
+			}
+			if current, ok := composed.Scopes[k]; ok && current != ScopeAny {
+				if current != layer.Scopes[k] && layer.Scopes[k] != ScopeAny {
+					l.errorf("scope-conflict", k, "%s has different scopes", k)
+				}
+				continue
+			}
+			composed.Scopes[k] = layer.Scopes[k]
+		}
+
+		for _, k := range sortedKeys(layer.Types) {
+			if composed.Types == nil {
+				composed.Types = make(map[string]OptionType)
+			}
+			if current, ok := composed.Types[k]; ok {
+				if !reflect.DeepEqual(current, layer.Types[k]) {
+					l.errorf("type-conflict", k, "%s has different types", k)
+				}
+				continue
+			}
+			composed.Types[k] = layer.Types[k]
+		}
+
+		// Layers without Normalizers inherit them. Earlier layers without
+		// Normalizers used the defaults.
+		if layer.Normalizers != nil {
+			if composed.Normalizers == nil {
+				composed.Normalizers = make(map[string]Normalizer)
+				if i > 0 {
+					composed.Normalizers = DefaultNormalizers()
+				}
+			}
+			for k, n := range layer.Normalizers {
+				composed.Normalizers[k] = n
+			}
+		}
+
+		composed.SloppyMount = composed.SloppyMount && layer.SloppyMount
+		if layer.LockedPolicy == RejectLockedOverride {
+			composed.LockedPolicy = RejectLockedOverride
+		}
+		if layer.UnsafeValues == RejectUnsafeValues {
+			composed.UnsafeValues = RejectUnsafeValues
+		}
+		if layer.AliasPrecedence == RejectAliasConflict || composed.AliasPrecedence != RejectAliasConflict {
+			composed.AliasPrecedence = layer.AliasPrecedence
+		}
+	}
+
+	composed.UnknownOptions = strictestUnknownOptions(layers)
+
+	// Later layers may only set values for options the composed mask allows,
+	// or that an earlier layer already set.
+	allowed, _ := compileKeySet(composed.Allowed)
+	for _, v := range introduced {
+		if allowed.match(v.key) == noMatch {
+			l.errorf(v.kind+"-widens", v.key, "%s has a %s value in a later layer but is not Allowed", v.key, v.kind)
+		}
+	}
+
+	diagnostics := append(l.diagnostics, LintMountOptsMask(composed)...)
+	if diagnostics.HasErrors() {
+		return MountOptsMask{}, &MaskError{Diagnostics: diagnostics}
+	}
+
+	return composed, nil
+}
+
+// passedThrough reports whether every layer accepts key as an unknown option.
+func passedThrough(layers []MountOptsMask, key string) bool {
+	for _, layer := range layers {
+		if layer.unknownOptionMode(key) != PassThroughUnknownOptions {
+			return false
+		}
+	}
+	return true
+}
+
+type staticValue struct {
+	key  string
+	kind string
+}
+
+func (m MountOptsMask) hasStaticValue(key string) bool {
+	_, isDefault := m.Defaults[key]
+	_, isLocked := m.Locked[key]
+	return isDefault || isLocked
+}
+
+// intersectAllowed keeps the entries of a allowed by b, and the exact entries
+// of b allowed by a.
+func intersectAllowed(a, b []string) []string {
+	// Invalid patterns match nothing; the composed mask is linted.
+	setA, _ := compileKeySet(a)
+	setB, _ := compileKeySet(b)
+
+	allowed := []string{}
+	for _, k := range a {
+		if isPattern(k) && inArray(b, k) || !isPattern(k) && setB.match(k) != noMatch {
+			allowed = appendMissing(allowed, []string{k})
+		}
+	}
+	for _, k := range b {
+		if !isPattern(k) && setA.match(k) != noMatch {
+			allowed = appendMissing(allowed, []string{k})
+		}
+	}
+	return allowed
+}
+
+func appendMissing(list []string, entries []string) []string {
+	for _, entry := range entries {
+		if !inArray(list, entry) {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+var unknownOptionStrictness = map[UnknownOptionMode]int{
+	PassThroughUnknownOptions:     1,
+	PassThroughPrefixedOptions:    2,
+	DropUnknownOptions:            3,
+	DropUnknownOptionsWithWarning: 4,
+	RejectUnknownOptions:          5,
+}
+
+func strictestUnknownOptions(layers []MountOptsMask) UnknownOptionPolicy {
+	var strictest UnknownOptionPolicy
+	for _, layer := range layers {
+		policy := layer.UnknownOptions
+		if policy.Mode == UnknownOptionsDefault {
+			continue
+		}
+
+		switch {
+		case strictest.Mode == UnknownOptionsDefault,
+			unknownOptionStrictness[policy.Mode] > unknownOptionStrictness[strictest.Mode]:
+			strictest = policy
+		case policy.Mode == PassThroughPrefixedOptions && strictest.Mode == PassThroughPrefixedOptions && policy.Prefix != strictest.Prefix:
+			strictest = UnknownOptionPolicy{Mode: RejectUnknownOptions}
+		}
+	}
+
+	// Layers without a policy reject unknown options unless they are sloppy.
+	for _, layer := range layers {
+		if layer.UnknownOptions.Mode != UnknownOptionsDefault || strictest.Mode == UnknownOptionsDefault {
+			continue
+		}
+		mode := RejectUnknownOptions
+		if layer.SloppyMount {
+			mode = DropUnknownOptionsWithWarning
+		}
+		if unknownOptionStrictness[mode] > unknownOptionStrictness[strictest.Mode] {
+			strictest = UnknownOptionPolicy{Mode: mode}
+		}
+	}
+
+	return strictest
+}
@@ -0,0 +1,288 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ComposeMasks", func() {
+	var (
+		platform vmo.MountOptsMask
+		plan     vmo.MountOptsMask
+		composed vmo.MountOptsMask
+		err      error
+	)
+
+	BeforeEach(func() {
+		platform = vmo.MountOptsMask{
+			Allowed:   []string{"uid", "gid", "vers", "x-systemd.*"},
+			Defaults:  map[string]interface{}{"vers": "4.1"},
+			Forbidden: map[string]string{"suid": "setuid binaries are not allowed"},
+			Locked:    map[string]interface{}{"nodev": true},
+			KeyPerms:  map[string]string{"UID": "uid"},
+		}
+		plan = vmo.MountOptsMask{
+			Allowed:   []string{"uid", "vers", "x-systemd.automount", "username"},
+			Defaults:  map[string]interface{}{"vers": "3"},
+			Mandatory: []string{"uid"},
+			Forbidden: map[string]string{"x-systemd.requires": "not supported"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		composed, err = vmo.ComposeMasks(platform, plan)
+	})
+
+	It("should intersect the allowed options", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(composed.Allowed).To(Equal([]string{"uid", "vers", "x-systemd.automount"}))
+	})
+
+	It("should let later layers override defaults", func() {
+		Expect(composed.Defaults).To(Equal(map[string]interface{}{"vers": "3"}))
+	})
+
+	It("should accumulate restrictions", func() {
+		Expect(composed.Mandatory).To(Equal([]string{"uid"}))
+		Expect(composed.Forbidden).To(HaveLen(2))
+		Expect(composed.Locked).To(Equal(map[string]interface{}{"nodev": true}))
+		Expect(composed.KeyPerms).To(Equal(map[string]string{"UID": "uid"}))
+	})
+
+	It("should only accept what every layer accepts", func() {
+		opts, err := vmo.NewMountOpts(map[string]interface{}{"UID": "1000"}, composed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(vmo.MountOpts{"uid": "1000", "vers": "3", "nodev": true}))
+
+		_, err = vmo.NewMountOpts(map[string]interface{}{"uid": "1000", "gid": "1000"}, composed)
+		Expect(err).To(MatchError("- Not allowed options: gid\n"))
+
+		_, err = vmo.NewMountOpts(map[string]interface{}{"uid": "1000", "username": "bob"}, composed)
+		Expect(err).To(MatchError("- Not allowed options: username\n"))
+	})
+
+	Context("when a layer does not list allowed options", func() {
+		BeforeEach(func() {
+			plan.Allowed = nil
+		})
+
+		It("should inherit them", func() {
+			Expect(composed.Allowed).To(Equal(platform.Allowed))
+		})
+	})
+
+	Context("when a later layer allows options an earlier layer does not", func() {
+		It("should return an error", func() {
+			_, err := vmo.ComposeMasks(vmo.MountOptsMask{}, vmo.MountOptsMask{Allowed: []string{"suid"}})
+			Expect(err).To(MatchError("suid is Allowed by a later layer but not by an earlier one"))
+		})
+
+		It("should accept options the earlier layer passes through", func() {
+			composed, err := vmo.ComposeMasks(
+				vmo.MountOptsMask{UnknownOptions: vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}},
+				vmo.MountOptsMask{Allowed: []string{"x-vendor.io"}},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.Allowed).To(Equal([]string{"x-vendor.io"}))
+		})
+	})
+
+	Context("when the layers share a pattern", func() {
+		BeforeEach(func() {
+			plan.Allowed = []string{"x-systemd.*", "uid"}
+		})
+
+		It("should keep the pattern", func() {
+			Expect(composed.Allowed).To(Equal([]string{"uid", "x-systemd.*"}))
+		})
+	})
+
+	Context("when the allowed options do not overlap", func() {
+		BeforeEach(func() {
+			plan.Allowed = []string{"username"}
+			plan.Mandatory = nil
+		})
+
+		It("should allow nothing", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.Allowed).To(BeEmpty())
+
+			_, err = vmo.NewMountOpts(map[string]interface{}{"uid": "1000"}, composed)
+			Expect(err).To(MatchError("- Not allowed options: uid\n"))
+		})
+	})
+
+	Context("when a later layer redefines a locked option", func() {
+		BeforeEach(func() {
+			plan.Locked = map[string]interface{}{"nodev": false}
+		})
+
+		It("should return an error", func() {
+			var maskErr *vmo.MaskError
+			Expect(errors.As(err, &maskErr)).To(BeTrue())
+			Expect(maskErr.Diagnostics.Errors()[0].Code).To(Equal("locked-conflict"))
+		})
+	})
+
+	Context("when a later layer sets values for options that are not allowed", func() {
+		It("should return an error", func() {
+			_, err := vmo.ComposeMasks(
+				vmo.MountOptsMask{Allowed: []string{"uid"}},
+				vmo.MountOptsMask{Defaults: map[string]interface{}{"suid": ""}, Locked: map[string]interface{}{"dev": ""}},
+			)
+			var maskErr *vmo.MaskError
+			Expect(errors.As(err, &maskErr)).To(BeTrue())
+			Expect(maskErr.Diagnostics.Errors()).To(HaveLen(2))
+			Expect(err).To(MatchError("suid has a default value in a later layer but is not Allowed; dev has a locked value in a later layer but is not Allowed"))
+		})
+
+		It("should reject forbidden options", func() {
+			plan.Defaults = map[string]interface{}{"suid": ""}
+			_, err := vmo.ComposeMasks(platform, plan)
+			Expect(err).To(MatchError(ContainSubstring("suid has a default but is Forbidden")))
+		})
+
+		It("should accept values for options an earlier layer set", func() {
+			plan.Locked = map[string]interface{}{"nodev": true}
+			_, err := vmo.ComposeMasks(platform, plan)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when a layer disables normalization", func() {
+		BeforeEach(func() {
+			platform.Allowed = append(platform.Allowed, "dircache")
+			platform.Normalizers = map[string]vmo.Normalizer{}
+		})
+
+		It("should return an equivalent mask for that layer alone", func() {
+			composed, err := vmo.ComposeMasks(platform)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.Normalizers).To(BeEmpty())
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{"dircache": true}, composed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(HaveKeyWithValue("dircache", "true"))
+		})
+
+		It("should keep it disabled when later layers inherit normalizers", func() {
+			plan.Allowed = append(plan.Allowed, "dircache")
+			composed, err := vmo.ComposeMasks(platform, plan)
+			Expect(err).NotTo(HaveOccurred())
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{"uid": "1000", "dircache": true}, composed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(HaveKeyWithValue("dircache", "true"))
+		})
+
+		It("should normalize with the defaults when an earlier layer has no normalizers", func() {
+			plan.Allowed = append(plan.Allowed, "dircache")
+			plan.Normalizers = map[string]vmo.Normalizer{"sec": vmo.Lowercase()}
+			composed, err := vmo.ComposeMasks(vmo.MountOptsMask{Allowed: platform.Allowed}, plan)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.Normalizers).To(HaveKey("dircache"))
+			Expect(composed.Normalizers).To(HaveKey("sec"))
+		})
+	})
+
+	Context("when no layer has normalizers", func() {
+		It("should leave them unset", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.Normalizers).To(BeNil())
+		})
+	})
+
+	Context("when a later layer aliases an ignored option", func() {
+		It("should keep ignoring it", func() {
+			composed, err := vmo.ComposeMasks(
+				vmo.MountOptsMask{Allowed: []string{"ro"}, Ignored: []string{"readonly"}},
+				vmo.MountOptsMask{KeyPerms: map[string]string{"readonly": "ro"}},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := vmo.EvaluateMountOpts(context.Background(), map[string]interface{}{"readonly": true}, composed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(BeEmpty())
+			Expect(result.Ignored).To(Equal([]string{"readonly"}))
+		})
+	})
+
+	Context("when a later layer redefines an alias", func() {
+		BeforeEach(func() {
+			plan.KeyPerms = map[string]string{"UID": "gid"}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(MatchError("alias UID refers to both uid and gid"))
+		})
+	})
+
+	Context("when a later layer makes a forbidden option mandatory", func() {
+		BeforeEach(func() {
+			plan.Mandatory = []string{"suid"}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("suid is both Mandatory and Forbidden")))
+		})
+	})
+
+	Context("when the layers have different policies", func() {
+		BeforeEach(func() {
+			platform.SloppyMount = true
+			platform.UnsafeValues = vmo.AllowUnsafeValues
+			platform.AliasPrecedence = vmo.RejectAliasConflict
+			plan.LockedPolicy = vmo.RejectLockedOverride
+			plan.AliasPrecedence = vmo.AliasWins
+		})
+
+		It("should use the strictest", func() {
+			Expect(composed.SloppyMount).To(BeFalse())
+			Expect(composed.UnsafeValues).To(Equal(vmo.RejectUnsafeValues))
+			Expect(composed.LockedPolicy).To(Equal(vmo.RejectLockedOverride))
+			Expect(composed.AliasPrecedence).To(Equal(vmo.RejectAliasConflict))
+		})
+	})
+
+	DescribeTable("unknown option policies",
+		func(platformPolicy, planPolicy, expected vmo.UnknownOptionPolicy) {
+			platform.UnknownOptions = platformPolicy
+			plan.UnknownOptions = planPolicy
+
+			composed, err := vmo.ComposeMasks(platform, plan)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(composed.UnknownOptions).To(Equal(expected))
+		},
+		Entry("no policies",
+			vmo.UnknownOptionPolicy{}, vmo.UnknownOptionPolicy{}, vmo.UnknownOptionPolicy{}),
+		Entry("dropping and passing through",
+			vmo.UnknownOptionPolicy{Mode: vmo.DropUnknownOptions}, vmo.UnknownOptionPolicy{Mode: vmo.PassThroughUnknownOptions},
+			vmo.UnknownOptionPolicy{Mode: vmo.DropUnknownOptions}),
+		Entry("passing through and a strict default",
+			vmo.UnknownOptionPolicy{Mode: vmo.PassThroughUnknownOptions}, vmo.UnknownOptionPolicy{},
+			vmo.UnknownOptionPolicy{Mode: vmo.RejectUnknownOptions}),
+		Entry("different prefixes",
+			vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}, vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "y-"},
+			vmo.UnknownOptionPolicy{Mode: vmo.RejectUnknownOptions}),
+	)
+
+	It("should accumulate validators", func() {
+		failing := vmo.UserOptsValidationFunc(func(key, value string) error {
+			if value == "0" {
+				return errors.New(key + " must not be 0")
+			}
+			return nil
+		})
+		platform.ValidationFunc = []vmo.UserOptsValidation{failing}
+
+		composed, err := vmo.ComposeMasks(platform, plan)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = vmo.NewMountOpts(map[string]interface{}{"uid": "0"}, composed)
+		Expect(err).To(MatchError("- validation mount options failed: uid must not be 0\n"))
+	})
+})
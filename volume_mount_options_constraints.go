@@ -0,0 +1,242 @@
+package volume_mount_options
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type ConditionOp string
+
+const (
+	OpPresent   ConditionOp = "present"
+	OpEquals    ConditionOp = "="
+	OpNotEquals ConditionOp = "!="
+	OpAtLeast   ConditionOp = ">="
+	OpAtMost    ConditionOp = "<="
+)
+
+// Condition tests a single option of a resolved MountOpts. OpEquals and
+// OpNotEquals match Value as a glob pattern; OpAtLeast and OpAtMost compare
+// numerically.
+type Condition struct {
+	Key   string
+	Op    ConditionOp
+	Value string
+}
+
+func Present(key string) Condition {
+	return Condition{Key: key, Op: OpPresent}
+}
+
+func Equals(key, pattern string) Condition {
+	return Condition{Key: key, Op: OpEquals, Value: pattern}
+}
+
+func NotEquals(key, pattern string) Condition {
+	return Condition{Key: key, Op: OpNotEquals, Value: pattern}
+}
+
+func AtLeast(key string, value float64) Condition {
+	return Condition{Key: key, Op: OpAtLeast, Value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+func AtMost(key string, value float64) Condition {
+	return Condition{Key: key, Op: OpAtMost, Value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+func (c Condition) String() string {
+	if c.Op == OpPresent {
+		return c.Key
+	}
+	return c.Key + string(c.Op) + c.Value
+}
+
+func (c Condition) holds(opts MountOpts) bool {
+	v, ok := opts[c.Key]
+	if !ok {
+		return false
+	}
+	value := fmt.Sprintf("%v", v)
+
+	switch c.Op {
+	case OpPresent:
+		return true
+	case OpEquals, OpNotEquals:
+		matched, err := path.Match(c.Value, value)
+		return err == nil && matched == (c.Op == OpEquals)
+	case OpAtLeast, OpAtMost:
+		actual, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return false
+		}
+		if c.Op == OpAtLeast {
+			return actual >= bound
+		}
+		return actual <= bound
+	}
+	return false
+}
+
+func (c Condition) lint() error {
+	if c.Key == "" {
+		return fmt.Errorf("condition has no key")
+	}
+
+	switch c.Op {
+	case OpPresent:
+	case OpEquals, OpNotEquals:
+		if _, err := path.Match(c.Value, ""); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", c.Key, c.Value, err)
+		}
+	case OpAtLeast, OpAtMost:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("%s: %q is not a number", c.Key, c.Value)
+		}
+	default:
+		return fmt.Errorf("%s: unknown condition operator %q", c.Key, c.Op)
+	}
+	return nil
+}
+
+type ConstraintKind string
+
+const (
+	MutuallyExclusiveConstraint ConstraintKind = "mutually-exclusive"
+	RequiresConstraint          ConstraintKind = "requires"
+	RequiresOneOfConstraint     ConstraintKind = "requires-one-of"
+	ForbiddenWhenConstraint     ConstraintKind = "forbidden-when"
+)
+
+// Constraint relates several options of a resolved MountOpts. If is the
+// subject of requires, requires-one-of and forbidden-when constraints and is
+// unused by mutually-exclusive ones.
+type Constraint struct {
+	Kind ConstraintKind
+	If   Condition
+	Then []Condition
+}
+
+func MutuallyExclusive(conditions ...Condition) Constraint {
+	return Constraint{Kind: MutuallyExclusiveConstraint, Then: conditions}
+}
+
+func Requires(subject Condition, required ...Condition) Constraint {
+	return Constraint{Kind: RequiresConstraint, If: subject, Then: required}
+}
+
+func RequiresOneOf(subject Condition, candidates ...Condition) Constraint {
+	return Constraint{Kind: RequiresOneOfConstraint, If: subject, Then: candidates}
+}
+
+func ForbiddenWhen(forbidden Condition, when Condition) Constraint {
+	return Constraint{Kind: ForbiddenWhenConstraint, If: when, Then: []Condition{forbidden}}
+}
+
+func (c Constraint) lint() error {
+	switch c.Kind {
+	case MutuallyExclusiveConstraint:
+	case RequiresConstraint, RequiresOneOfConstraint, ForbiddenWhenConstraint:
+		if err := c.If.lint(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown constraint kind %q", c.Kind)
+	}
+
+	if len(c.Then) == 0 {
+		return fmt.Errorf("%s constraint has no conditions", c.Kind)
+	}
+	for _, cond := range c.Then {
+		if err := cond.lint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subject returns the key a constraint is reported under.
+func (c Constraint) subject() string {
+	if c.Kind == MutuallyExclusiveConstraint && len(c.Then) > 0 {
+		return c.Then[0].Key
+	}
+	return c.If.Key
+}
+
+func (c Constraint) check(opts MountOpts) *Violation {
+	switch c.Kind {
+	case MutuallyExclusiveConstraint:
+		var holding []Condition
+		for _, cond := range c.Then {
+			if cond.holds(opts) {
+				holding = append(holding, cond)
+			}
+		}
+		if len(holding) > 1 {
+			return constraintViolation(holding[0], holding[1:], "%s conflicts with %s")
+		}
+
+	case RequiresConstraint:
+		if !c.If.holds(opts) {
+			return nil
+		}
+		var missing []Condition
+		for _, cond := range c.Then {
+			if !cond.holds(opts) {
+				missing = append(missing, cond)
+			}
+		}
+		if len(missing) > 0 {
+			return constraintViolation(c.If, missing, "%s requires %s")
+		}
+
+	case RequiresOneOfConstraint:
+		if !c.If.holds(opts) {
+			return nil
+		}
+		for _, cond := range c.Then {
+			if cond.holds(opts) {
+				return nil
+			}
+		}
+		return constraintViolation(c.If, c.Then, "%s requires one of %s")
+
+	case ForbiddenWhenConstraint:
+		if !c.If.holds(opts) {
+			return nil
+		}
+		for _, cond := range c.Then {
+			if cond.holds(opts) {
+				return constraintViolation(cond, []Condition{c.If}, "%s is forbidden when %s")
+			}
+		}
+
+	default:
+		return &Violation{
+			Kind:  ErrConstraintViolated,
+			Cause: fmt.Errorf("unknown constraint kind %q", c.Kind),
+		}
+	}
+
+	return nil
+}
+
+func constraintViolation(subject Condition, related []Condition, format string) *Violation {
+	var relatedKeys, relatedDesc []string
+	for _, cond := range related {
+		relatedKeys = append(relatedKeys, cond.Key)
+		relatedDesc = append(relatedDesc, cond.String())
+	}
+
+	return &Violation{
+		Kind:    ErrConstraintViolated,
+		Key:     subject.Key,
+		Related: relatedKeys,
+		Cause:   fmt.Errorf(format, subject.String(), strings.Join(relatedDesc, ", ")),
+	}
+}
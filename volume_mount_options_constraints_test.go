@@ -0,0 +1,118 @@
+package volume_mount_options_test
+
+import (
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Constraints", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		actualRes vmo.MountOpts
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMountOptsMask(
+			[]string{"ro", "rw", "uid", "gid", "username", "sec", "vers", "suid"},
+			map[string]interface{}{"vers": "3"},
+			map[string]string{"version": "vers"},
+			[]string{},
+			[]string{},
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		mask.Constraints = []vmo.Constraint{
+			vmo.MutuallyExclusive(vmo.Present("ro"), vmo.Present("rw")),
+			vmo.Requires(vmo.Present("gid"), vmo.Present("uid")),
+			vmo.RequiresOneOf(vmo.Present("uid"), vmo.Present("username"), vmo.Present("gid")),
+			vmo.Requires(vmo.Equals("sec", "krb5*"), vmo.AtLeast("vers", 4)),
+			vmo.ForbiddenWhen(vmo.Present("suid"), vmo.NotEquals("uid", "0")),
+		}
+	})
+
+	JustBeforeEach(func() {
+		actualRes, err = vmo.NewMountOpts(userInput, mask)
+	})
+
+	Context("when the options satisfy every constraint", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{
+				"ro":      true,
+				"uid":     0,
+				"gid":     0,
+				"sec":     "krb5p",
+				"version": "4.1",
+				"suid":    "",
+			}
+		})
+
+		It("should return the options", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualRes).To(HaveKeyWithValue("vers", "4.1"))
+		})
+	})
+
+	Context("when mutually exclusive options are supplied", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"ro": true, "rw": true}
+		})
+
+		It("should return an error naming both options", func() {
+			Expect(err).To(MatchError("- Option constraints violated: ro conflicts with rw\n"))
+
+			var mountOptsErr *vmo.MountOptsError
+			Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+			violations := mountOptsErr.ViolationsOf(vmo.ErrConstraintViolated)
+			Expect(violations).To(HaveLen(1))
+			Expect(violations[0].Key).To(Equal("ro"))
+			Expect(violations[0].Related).To(Equal([]string{"rw"}))
+		})
+	})
+
+	Context("when a required option is missing", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"gid": "1000"}
+		})
+
+		It("should return an error", func() {
+			Expect(errors.Is(err, vmo.ErrConstraintViolated)).To(BeTrue())
+			Expect(err).To(MatchError("- Option constraints violated: gid requires uid\n"))
+		})
+	})
+
+	Context("when none of a set of required options is present", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"uid": "1000"}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(MatchError("- Option constraints violated: uid requires one of username, gid\n"))
+		})
+	})
+
+	Context("when a value condition is not met after defaults are applied", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"sec": "krb5i"}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(MatchError("- Option constraints violated: sec=krb5* requires vers>=4\n"))
+		})
+	})
+
+	Context("when a forbidden option is supplied", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"uid": "1000", "username": "bob", "suid": ""}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(MatchError("- Option constraints violated: suid is forbidden when uid!=0\n"))
+		})
+	})
+})
@@ -0,0 +1,120 @@
+package volume_mount_options
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrValidationFailed   = errors.New("validation mount options failed")
+	ErrNotAllowed         = errors.New("option not allowed")
+	ErrMissingOption      = errors.New("missing mandatory option")
+	ErrUnsafeOption       = errors.New("unsafe option")
+	ErrConstraintViolated = errors.New("option constraint violated")
+	ErrAliasConflict      = errors.New("conflicting values for aliased option")
+	ErrForbiddenOption    = errors.New("forbidden option")
+	ErrLockedOption       = errors.New("locked option")
+	ErrWrongScope         = errors.New("option not settable at this scope")
+)
+
+// Violation describes why a single option was rejected. Kind is one of the
+// Err* sentinels and can be matched with errors.Is. Related names the other
+// options involved in a constraint violation.
+type Violation struct {
+	Kind    error
+	Key     string
+	Value   interface{}
+	Cause   error
+	Related []string
+}
+
+func (v *Violation) Error() string {
+	if v.Cause != nil {
+		return fmt.Sprintf("%s: %s: %s", v.Key, v.Kind.Error(), v.Cause.Error())
+	}
+	return fmt.Sprintf("%s: %s", v.Key, v.Kind.Error())
+}
+
+func (v *Violation) Unwrap() []error {
+	if v.Cause != nil {
+		return []error{v.Kind, v.Cause}
+	}
+	return []error{v.Kind}
+}
+
+func (v *Violation) item() string {
+	switch {
+	case (v.Kind == ErrValidationFailed || v.Kind == ErrConstraintViolated) && v.Cause != nil:
+		return v.Cause.Error()
+	case v.Kind == ErrUnsafeOption && v.Cause != nil:
+		return fmt.Sprintf("%q (%s)", v.Key, v.Cause.Error())
+	case (v.Kind == ErrForbiddenOption || v.Kind == ErrNotAllowed || v.Kind == ErrWrongScope) && v.Cause != nil:
+		return fmt.Sprintf("%s (%s)", v.Key, v.Cause.Error())
+	case v.Kind == ErrAliasConflict:
+		return fmt.Sprintf("%s (%s)", v.Key, strings.Join(v.Related, ", "))
+	}
+	return v.Key
+}
+
+// MountOptsError is returned by NewMountOpts when one or more options are
+// rejected. Its message keeps the historical line-per-kind format.
+type MountOptsError struct {
+	Violations []*Violation
+}
+
+var errorSections = []struct {
+	kind   error
+	format string
+}{
+	{ErrValidationFailed, ValidationFailErrorMessage},
+	{ErrNotAllowed, NotAllowedErrorMessage},
+	{ErrMissingOption, MissingOptionErrorMessage},
+	{ErrUnsafeOption, UnsafeOptionErrorMessage},
+	{ErrConstraintViolated, ConstraintErrorMessage},
+	{ErrAliasConflict, AliasConflictErrorMessage},
+	{ErrForbiddenOption, ForbiddenOptionErrorMessage},
+	{ErrLockedOption, LockedOptionErrorMessage},
+	{ErrWrongScope, WrongScopeErrorMessage},
+}
+
+func (e *MountOptsError) Error() string {
+	var b strings.Builder
+	for _, section := range errorSections {
+		var items []string
+		for _, v := range e.Violations {
+			if v.Kind == section.kind {
+				items = append(items, v.item())
+			}
+		}
+		b.WriteString(buildErrorMessage(items, section.format))
+	}
+	return b.String()
+}
+
+func (e *MountOptsError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// sortViolations orders violations by key. The sort is stable so violations
+// of the same key keep the order in which the validators reported them.
+func sortViolations(violations []*Violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Key < violations[j].Key
+	})
+}
+
+func (e *MountOptsError) ViolationsOf(kind error) []*Violation {
+	var violations []*Violation
+	for _, v := range e.Violations {
+		if errors.Is(v.Kind, kind) {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
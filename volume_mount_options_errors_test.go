@@ -0,0 +1,95 @@
+package volume_mount_options_test
+
+import (
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MountOptsError", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMountOptsMask(
+			[]string{"opt1", "required1"},
+			map[string]interface{}{},
+			map[string]string{},
+			[]string{},
+			[]string{"required1"},
+			vmo.UserOptsValidationFunc(func(key, value string) error {
+				if value == "bad" {
+					return errors.New("opt1 is bad")
+				}
+				return nil
+			}),
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		userInput = map[string]interface{}{
+			"opt1":       "bad",
+			"notallowed": "foo",
+		}
+	})
+
+	JustBeforeEach(func() {
+		_, err = vmo.NewMountOpts(userInput, mask)
+	})
+
+	It("should be returned as a MountOptsError", func() {
+		var mountOptsErr *vmo.MountOptsError
+		Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+		Expect(mountOptsErr.Violations).To(HaveLen(3))
+	})
+
+	It("should match each sentinel kind", func() {
+		Expect(errors.Is(err, vmo.ErrValidationFailed)).To(BeTrue())
+		Expect(errors.Is(err, vmo.ErrNotAllowed)).To(BeTrue())
+		Expect(errors.Is(err, vmo.ErrMissingOption)).To(BeTrue())
+	})
+
+	It("should describe each violation", func() {
+		var mountOptsErr *vmo.MountOptsError
+		Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+
+		notAllowed := mountOptsErr.ViolationsOf(vmo.ErrNotAllowed)
+		Expect(notAllowed).To(HaveLen(1))
+		Expect(notAllowed[0].Key).To(Equal("notallowed"))
+		Expect(notAllowed[0].Value).To(Equal("foo"))
+		Expect(notAllowed[0].Error()).To(Equal("notallowed: option not allowed"))
+
+		failed := mountOptsErr.ViolationsOf(vmo.ErrValidationFailed)
+		Expect(failed).To(HaveLen(1))
+		Expect(failed[0].Key).To(Equal("opt1"))
+		Expect(failed[0].Cause).To(MatchError("opt1 is bad"))
+
+		missing := mountOptsErr.ViolationsOf(vmo.ErrMissingOption)
+		Expect(missing).To(HaveLen(1))
+		Expect(missing[0].Key).To(Equal("required1"))
+	})
+
+	It("should keep the historical error message", func() {
+		Expect(err).To(MatchError(`- validation mount options failed: opt1 is bad
+- Not allowed options: notallowed
+- Missing mandatory options: required1
+`))
+	})
+
+	Context("when only mandatory options are missing", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{}
+		})
+
+		It("should only match the missing option kind", func() {
+			Expect(errors.Is(err, vmo.ErrMissingOption)).To(BeTrue())
+			Expect(errors.Is(err, vmo.ErrNotAllowed)).To(BeFalse())
+			Expect(errors.Is(err, vmo.ErrValidationFailed)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package volume_mount_options
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// OptsLayer is one source of option values for MergeMountOpts, such as the
+// service instance configuration or the binding parameters. When Keys is not
+// empty, the layer may only set options matching Keys, which may be
+// patterns. When Scope is set, the layer may only set options of that scope.
+type OptsLayer struct {
+	Name  string
+	Opts  map[string]interface{}
+	Keys  []string
+	Scope OptionScope
+}
+
+// MergeMountOpts merges layers, later layers overriding earlier ones, and
+// evaluates the result against mask like EvaluateMountOpts. Aliases are
+// resolved before merging, so a layer overrides an option whichever key it
+// is supplied under. The Provenance of user options names the winning
+// layer.
+func MergeMountOpts(ctx context.Context, mask MountOptsMask, layers ...OptsLayer) (*MountOptsResult, error) {
+	compiled, err := Compile(mask)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Merge(ctx, layers...)
+}
+
+// Merge is the compiled equivalent of MergeMountOpts.
+func (c *CompiledMask) Merge(ctx context.Context, layers ...OptsLayer) (*MountOptsResult, error) {
+	userOpts := make(map[string]interface{})
+	layerOf := make(map[string]string)
+	keysOf := make(map[string][]string)
+	ownerOf := make(map[string]int)
+
+	var violations []*Violation
+	for i, layer := range layers {
+		keys, err := compileKeySet(layer.Keys)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range sortedKeys(layer.Opts) {
+			canonicalKey := c.canonicalKey(k)
+			if len(layer.Keys) > 0 && keys.match(canonicalKey) == noMatch && keys.match(k) == noMatch {
+				violations = append(violations, &Violation{
+					Kind:  ErrNotAllowed,
+					Key:   k,
+					Value: layer.Opts[k],
+					Cause: fmt.Errorf("not settable by %s", layer.Name),
+				})
+				continue
+			}
+			if violation := c.checkScope(k, canonicalKey, layer.Opts[k], layer.Scope); violation != nil {
+				violations = append(violations, violation)
+				continue
+			}
+
+			if owner, ok := ownerOf[canonicalKey]; ok && owner != i {
+				for _, prev := range keysOf[canonicalKey] {
+					delete(userOpts, prev)
+					delete(layerOf, prev)
+				}
+				delete(keysOf, canonicalKey)
+			}
+			userOpts[k] = layer.Opts[k]
+			layerOf[k] = layer.Name
+			keysOf[canonicalKey] = append(keysOf[canonicalKey], k)
+			ownerOf[canonicalKey] = i
+		}
+	}
+
+	result, err := c.Evaluate(ctx, userOpts)
+	if result == nil {
+		return nil, err
+	}
+
+	for key, p := range result.Provenance {
+		if p.Source == SourceUser {
+			p.Layer = layerOf[p.Key]
+			result.Provenance[key] = p
+		}
+	}
+
+	if len(violations) > 0 {
+		var mountOptsErr *MountOptsError
+		if errors.As(err, &mountOptsErr) {
+			violations = append(violations, mountOptsErr.Violations...)
+		}
+		sortViolations(violations)
+		result.Options = MountOpts{}
+		result.Provenance = map[string]Provenance{}
+		return result, &MountOptsError{Violations: violations}
+	}
+
+	return result, err
+}
+
+func (c *CompiledMask) canonicalKey(key string) string {
+	if canonicalKey, ok := c.mask.KeyPerms[key]; ok {
+		return canonicalKey
+	}
+	return key
+}
@@ -0,0 +1,102 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeMountOpts", func() {
+	var (
+		mask     vmo.MountOptsMask
+		instance vmo.OptsLayer
+		binding  vmo.OptsLayer
+		result   *vmo.MountOptsResult
+		err      error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMask(
+			vmo.WithAllowed("uid", "gid", "vers", "readonly"),
+			vmo.WithDefault("vers", "3"),
+			vmo.WithAlias("UID", "uid"),
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		instance = vmo.OptsLayer{
+			Name: "instance",
+			Opts: map[string]interface{}{"uid": "1000", "gid": "1000", "vers": "4.1"},
+		}
+		binding = vmo.OptsLayer{
+			Name: "binding",
+			Opts: map[string]interface{}{"UID": "2000", "readonly": "true"},
+			Keys: []string{"uid", "readonly"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = vmo.MergeMountOpts(context.Background(), mask, instance, binding)
+	})
+
+	It("should let later layers override earlier ones", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Options).To(Equal(vmo.MountOpts{
+			"uid":      "2000",
+			"gid":      "1000",
+			"vers":     "4.1",
+			"readonly": "true",
+		}))
+	})
+
+	It("should record the winning layer", func() {
+		Expect(result.Provenance["uid"]).To(Equal(vmo.Provenance{Source: vmo.SourceUser, Key: "UID", RawValue: "2000", Layer: "binding"}))
+		Expect(result.Provenance["gid"].Layer).To(Equal("instance"))
+		Expect(result.Explain()).To(Equal([]string{
+			"gid=1000: user (instance)",
+			"readonly=true: user (binding)",
+			"uid=2000: user (binding), via alias UID",
+			"vers=4.1: user (instance)",
+		}))
+	})
+
+	Context("when a layer sets a key it is not allowed to set", func() {
+		BeforeEach(func() {
+			binding.Opts["gid"] = "0"
+		})
+
+		It("should return an error naming the layer", func() {
+			Expect(errors.Is(err, vmo.ErrNotAllowed)).To(BeTrue())
+			Expect(err).To(MatchError("- Not allowed options: gid (not settable by binding)\n"))
+			Expect(result.Options).To(BeEmpty())
+		})
+	})
+
+	Context("when the merged options are invalid", func() {
+		BeforeEach(func() {
+			binding.Keys = nil
+			binding.Opts["bogus"] = "x"
+		})
+
+		It("should return the mask's error", func() {
+			Expect(err).To(MatchError("- Not allowed options: bogus\n"))
+		})
+	})
+
+	Context("when there are no layers", func() {
+		It("should return the defaults", func() {
+			result, err := vmo.MergeMountOpts(context.Background(), mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(Equal(vmo.MountOpts{"vers": "3"}))
+		})
+	})
+
+	It("should reject invalid key patterns", func() {
+		binding.Keys = []string{"x-["}
+		_, err := vmo.MergeMountOpts(context.Background(), mask, binding)
+		Expect(err).To(MatchError(ContainSubstring("invalid pattern")))
+	})
+})
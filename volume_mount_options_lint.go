@@ -0,0 +1,203 @@
+package volume_mount_options
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Key      string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Severity, d.Code, d.Message)
+}
+
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) HasErrors() bool {
+	return len(d.Errors()) > 0
+}
+
+func (d Diagnostics) Errors() Diagnostics {
+	var errs Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			errs = append(errs, diagnostic)
+		}
+	}
+	return errs
+}
+
+// MaskError is returned by NewMountOptsMask when the mask contradicts itself.
+type MaskError struct {
+	Diagnostics Diagnostics
+}
+
+func (e *MaskError) Error() string {
+	var messages []string
+	for _, d := range e.Diagnostics.Errors() {
+		messages = append(messages, d.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// LintMountOptsMask looks for contradictions in mask. Diagnostics with
+// SeverityError describe masks that cannot behave as intended and are
+// rejected by NewMountOptsMask; warnings describe entries that have no
+// effect.
+func LintMountOptsMask(mask MountOptsMask) Diagnostics {
+	l := &linter{}
+
+	for _, list := range []struct {
+		name    string
+		entries []string
+	}{
+		{"Allowed", mask.Allowed},
+		{"Ignored", mask.Ignored},
+		{"Mandatory", mask.Mandatory},
+		{"Forbidden", sortedKeys(mask.Forbidden)},
+	} {
+		seen := make(map[string]bool)
+		for _, entry := range list.entries {
+			if seen[entry] {
+				l.warnf("duplicate", entry, "%s is listed more than once in %s", entry, list.name)
+			}
+			seen[entry] = true
+
+			if isPattern(entry) {
+				if _, err := compilePattern(entry); err != nil {
+					l.errorf("invalid-pattern", entry, "%s", err.Error())
+				}
+			}
+		}
+	}
+
+	// Invalid patterns were reported above and match nothing.
+	allowed, _ := compileKeySet(mask.Allowed)
+	ignored, _ := compileKeySet(mask.Ignored)
+	forbidden, _ := compileForbidden(mask.Forbidden)
+
+	// knows reports whether key can end up in the options produced by the
+	// mask.
+	knows := func(key string) bool {
+		_, isDefault := mask.Defaults[key]
+		_, isLocked := mask.Locked[key]
+		return isDefault || isLocked || allowed.match(key) != noMatch
+	}
+
+	for _, k := range mask.Mandatory {
+		if ignored.match(k) != noMatch {
+			l.errorf("mandatory-ignored", k, "%s is both Mandatory and Ignored", k)
+		}
+		if _, ok := forbidden.lookup(k, k); ok {
+			l.errorf("mandatory-forbidden", k, "%s is both Mandatory and Forbidden", k)
+		}
+		// Unless it is passed through, the key can never be supplied.
+		if !knows(k) && !isPattern(k) && mask.unknownOptionMode(k) != PassThroughUnknownOptions {
+			l.errorf("mandatory-not-allowed", k, "%s is Mandatory but not Allowed and has no default", k)
+		}
+	}
+
+	for _, k := range mask.Allowed {
+		if _, ok := forbidden.lookup(k, k); ok && !isPattern(k) {
+			l.warnf("allowed-forbidden", k, "%s is both Allowed and Forbidden", k)
+		}
+	}
+
+	for _, k := range sortedKeys(mask.Defaults) {
+		if _, ok := forbidden.lookup(k, k); ok {
+			l.errorf("default-forbidden", k, "%s has a default but is Forbidden", k)
+		} else if k != "sloppy_mount" && allowed.match(k) == noMatch {
+			l.warnf("default-not-allowed", k, "%s has a default but is not Allowed", k)
+		}
+	}
+
+	for _, k := range sortedKeys(mask.Locked) {
+		if _, ok := forbidden.lookup(k, k); ok {
+			l.errorf("locked-forbidden", k, "%s is both Locked and Forbidden", k)
+		}
+	}
+
+	for _, alias := range sortedKeys(mask.KeyPerms) {
+		canonicalKey := mask.KeyPerms[alias]
+		if !knows(canonicalKey) && ignored.match(canonicalKey) == noMatch {
+			l.warnf("alias-unknown-target", alias, "alias %s refers to %s, which is not Allowed", alias, canonicalKey)
+		}
+	}
+
+	for _, k := range sortedKeys(mask.Types) {
+		if allowed.match(k) == noMatch {
+			l.warnf("type-not-allowed", k, "%s has a type but is not Allowed", k)
+		}
+		if err := mask.Types[k].lint(); err != nil {
+			l.errorf("invalid-type", k, "%s: %s", k, err.Error())
+			continue
+		}
+
+		normalizers := mask.Normalizers
+		if normalizers == nil {
+			normalizers = DefaultNormalizers()
+		}
+		if v, ok := mask.Defaults[k]; ok {
+			if _, err := coerceValue(normalizers, mask.Types, k, v); err != nil {
+				l.errorf("invalid-default", k, "default of %s: %s", k, err.Error())
+			}
+		}
+		if v, ok := mask.Locked[k]; ok {
+			if _, err := coerceValue(normalizers, mask.Types, k, v); err != nil {
+				l.errorf("invalid-locked", k, "locked value of %s: %s", k, err.Error())
+			}
+		}
+	}
+
+	for _, c := range mask.Constraints {
+		if err := c.lint(); err != nil {
+			l.errorf("invalid-constraint", c.subject(), "invalid constraint: %s", err.Error())
+		}
+	}
+
+	for _, k := range sortedKeys(mask.Scopes) {
+		switch mask.Scopes[k] {
+		case ScopeAny, ScopeInstance, ScopeBinding:
+		default:
+			l.errorf("invalid-scope", k, "%s has an unknown scope %q", k, mask.Scopes[k])
+		}
+		if allowed.match(k) == noMatch {
+			l.warnf("scope-not-allowed", k, "%s has a scope but is not Allowed", k)
+		}
+	}
+
+	return l.diagnostics
+}
+
+type linter struct {
+	diagnostics Diagnostics
+}
+
+func (l *linter) errorf(code, key, format string, args ...interface{}) {
+	l.add(SeverityError, code, key, format, args...)
+}
+
+func (l *linter) warnf(code, key, format string, args ...interface{}) {
+	l.add(SeverityWarning, code, key, format, args...)
+}
+
+func (l *linter) add(severity Severity, code, key, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Key:      key,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
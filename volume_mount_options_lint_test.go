@@ -0,0 +1,101 @@
+package volume_mount_options_test
+
+import (
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("#LintMountOptsMask", func() {
+	var mask vmo.MountOptsMask
+
+	BeforeEach(func() {
+		mask = vmo.MountOptsMask{
+			Allowed:  []string{"uid", "gid"},
+			Defaults: map[string]interface{}{"sloppy_mount": "true", "uid": "1000"},
+			KeyPerms: map[string]string{"UID": "uid"},
+		}
+	})
+
+	It("should not report anything for a consistent mask", func() {
+		Expect(vmo.LintMountOptsMask(mask)).To(BeEmpty())
+	})
+
+	It("should report contradictions", func() {
+		mask.Allowed = []string{"uid", "uid", "suid", "x-["}
+		mask.Ignored = []string{"readonly"}
+		mask.Mandatory = []string{"readonly", "dev", "source"}
+		mask.Forbidden = map[string]string{"suid": "", "dev": "", "nodev": ""}
+		mask.Defaults = map[string]interface{}{"sloppy_mount": "true", "vers": "3", "nodev": ""}
+		mask.Locked = map[string]interface{}{"suid": true}
+		mask.KeyPerms = map[string]string{"version": "vers", "user": "username"}
+		mask.Types = map[string]vmo.OptionType{"uid": vmo.EnumType(), "rsize": vmo.IntRangeType(10, 1)}
+		mask.Scopes = map[string]vmo.OptionScope{"uid": vmo.ScopeBinding, "rsize": "plan"}
+
+		Expect(vmo.LintMountOptsMask(mask)).To(Equal(vmo.Diagnostics{
+			{Severity: vmo.SeverityWarning, Code: "duplicate", Key: "uid", Message: "uid is listed more than once in Allowed"},
+			{Severity: vmo.SeverityError, Code: "invalid-pattern", Key: "x-[", Message: `invalid pattern "x-[": syntax error in pattern`},
+			{Severity: vmo.SeverityError, Code: "mandatory-ignored", Key: "readonly", Message: "readonly is both Mandatory and Ignored"},
+			{Severity: vmo.SeverityError, Code: "mandatory-not-allowed", Key: "readonly", Message: "readonly is Mandatory but not Allowed and has no default"},
+			{Severity: vmo.SeverityError, Code: "mandatory-forbidden", Key: "dev", Message: "dev is both Mandatory and Forbidden"},
+			{Severity: vmo.SeverityError, Code: "mandatory-not-allowed", Key: "dev", Message: "dev is Mandatory but not Allowed and has no default"},
+			{Severity: vmo.SeverityError, Code: "mandatory-not-allowed", Key: "source", Message: "source is Mandatory but not Allowed and has no default"},
+			{Severity: vmo.SeverityWarning, Code: "allowed-forbidden", Key: "suid", Message: "suid is both Allowed and Forbidden"},
+			{Severity: vmo.SeverityError, Code: "default-forbidden", Key: "nodev", Message: "nodev has a default but is Forbidden"},
+			{Severity: vmo.SeverityWarning, Code: "default-not-allowed", Key: "vers", Message: "vers has a default but is not Allowed"},
+			{Severity: vmo.SeverityError, Code: "locked-forbidden", Key: "suid", Message: "suid is both Locked and Forbidden"},
+			{Severity: vmo.SeverityWarning, Code: "alias-unknown-target", Key: "user", Message: "alias user refers to username, which is not Allowed"},
+			{Severity: vmo.SeverityWarning, Code: "type-not-allowed", Key: "rsize", Message: "rsize has a type but is not Allowed"},
+			{Severity: vmo.SeverityError, Code: "invalid-type", Key: "rsize", Message: "rsize: minimum 10 is greater than maximum 1"},
+			{Severity: vmo.SeverityError, Code: "invalid-type", Key: "uid", Message: "uid: enum type has no values"},
+			{Severity: vmo.SeverityError, Code: "invalid-scope", Key: "rsize", Message: `rsize has an unknown scope "plan"`},
+			{Severity: vmo.SeverityWarning, Code: "scope-not-allowed", Key: "rsize", Message: "rsize has a scope but is not Allowed"},
+		}))
+	})
+
+	It("should accept Mandatory options that are passed through", func() {
+		mask.Mandatory = []string{"x-source", "source"}
+		mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}
+
+		Expect(vmo.LintMountOptsMask(mask)).To(Equal(vmo.Diagnostics{
+			{Severity: vmo.SeverityError, Code: "mandatory-not-allowed", Key: "source", Message: "source is Mandatory but not Allowed and has no default"},
+		}))
+	})
+
+	DescribeTable("with invalid constraints",
+		func(constraint vmo.Constraint, key, message string) {
+			mask.Constraints = []vmo.Constraint{constraint}
+			Expect(vmo.LintMountOptsMask(mask)).To(Equal(vmo.Diagnostics{
+				{Severity: vmo.SeverityError, Code: "invalid-constraint", Key: key, Message: message},
+			}))
+		},
+		Entry("an unknown kind", vmo.Constraint{Kind: "implies", If: vmo.Present("uid"), Then: []vmo.Condition{vmo.Present("gid")}}, "uid", `invalid constraint: unknown constraint kind "implies"`),
+		Entry("no conditions", vmo.Requires(vmo.Present("uid")), "uid", "invalid constraint: requires constraint has no conditions"),
+		Entry("a bad glob", vmo.MutuallyExclusive(vmo.Equals("uid", "[0"), vmo.Present("gid")), "uid", `invalid constraint: uid: invalid pattern "[0": syntax error in pattern`),
+		Entry("a non-numeric bound", vmo.Requires(vmo.Present("uid"), vmo.Condition{Key: "gid", Op: vmo.OpAtLeast, Value: "many"}), "uid", `invalid constraint: gid: "many" is not a number`),
+		Entry("an unknown operator", vmo.ForbiddenWhen(vmo.Present("gid"), vmo.Condition{Key: "uid", Op: "~"}), "uid", `invalid constraint: uid: unknown condition operator "~"`),
+	)
+
+	Describe("#NewMountOptsMask", func() {
+		It("should fail when the mask has errors", func() {
+			_, err := vmo.NewMountOptsMask([]string{"uid", "readonly"}, nil, nil, []string{"readonly"}, []string{"readonly"})
+
+			var maskErr *vmo.MaskError
+			Expect(errors.As(err, &maskErr)).To(BeTrue())
+			Expect(maskErr.Diagnostics.Errors()).To(HaveLen(1))
+			Expect(err).To(MatchError("readonly is both Mandatory and Ignored"))
+		})
+
+		It("should succeed when the mask only has warnings", func() {
+			_, err := vmo.NewMountOptsMask([]string{}, map[string]interface{}{"vers": "3"}, map[string]string{"a": "b"}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	It("should format diagnostics", func() {
+		d := vmo.Diagnostic{Severity: vmo.SeverityError, Code: "mandatory-ignored", Key: "a", Message: "a is both Mandatory and Ignored"}
+		Expect(d.String()).To(Equal("error: mandatory-ignored: a is both Mandatory and Ignored"))
+	})
+})
@@ -1,20 +1,29 @@
 package volume_mount_options
 
 import (
-	"fmt"
-	"strconv"
-
-	"code.cloudfoundry.org/volume-mount-options/utils"
+	"context"
+	"strings"
 )
 
 type MountOptsMask struct {
-	Allowed        []string
-	Defaults       map[string]interface{}
-	KeyPerms       map[string]string
-	Ignored        []string
-	Mandatory      []string
-	SloppyMount    bool
-	ValidationFunc []UserOptsValidation
+	Allowed            []string
+	Defaults           map[string]interface{}
+	KeyPerms           map[string]string
+	Ignored            []string
+	Mandatory          []string
+	Forbidden          map[string]string
+	Locked             map[string]interface{}
+	Scopes             map[string]OptionScope
+	LockedPolicy       LockedPolicy
+	SloppyMount        bool
+	ValidationFunc     []UserOptsValidation
+	Types              map[string]OptionType
+	UnsafeValues       UnsafeValuePolicy
+	Constraints        []Constraint
+	Normalizers        map[string]Normalizer
+	AliasPrecedence    AliasPrecedence
+	UnknownOptions     UnknownOptionPolicy
+	OptsValidationFunc []MountOptsValidation
 }
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -29,34 +38,112 @@ func (v UserOptsValidationFunc) Validate(a string, b string) error {
 	return v(a, b)
 }
 
-func NewMountOptsMask(allowed []string,
-	defaults map[string]interface{},
-	keyPerms map[string]string,
-	ignored, mandatory []string,
-	f ...UserOptsValidation) (MountOptsMask, error) {
-	mask := MountOptsMask{
-		Allowed:        allowed,
-		Defaults:       defaults,
-		KeyPerms:       keyPerms,
-		Ignored:        ignored,
-		Mandatory:      mandatory,
-		ValidationFunc: f,
-	}
+type UnknownOptionMode int
 
-	if defaults == nil {
-		mask.Defaults = make(map[string]interface{})
-	}
+const (
+	UnknownOptionsDefault UnknownOptionMode = iota
+	RejectUnknownOptions
+	DropUnknownOptions
+	DropUnknownOptionsWithWarning
+	PassThroughUnknownOptions
+	PassThroughPrefixedOptions
+)
 
-	if v, ok := defaults["sloppy_mount"]; ok {
-		vc := utils.InterfaceToString(v)
+// UnknownOptionPolicy decides what happens to user options that are not
+// Allowed. UnknownOptionsDefault rejects them, or drops them with a warning
+// when SloppyMount is set. PassThroughPrefixedOptions passes through options
+// starting with Prefix and rejects the rest.
+type UnknownOptionPolicy struct {
+	Mode   UnknownOptionMode
+	Prefix string
+}
 
-		var err error
-		mask.SloppyMount, err = strconv.ParseBool(vc)
+func (m MountOptsMask) unknownOptionMode(key string) UnknownOptionMode {
+	switch m.UnknownOptions.Mode {
+	case UnknownOptionsDefault:
+		if m.SloppyMount {
+			return DropUnknownOptionsWithWarning
+		}
+		return RejectUnknownOptions
 
-		if err != nil {
-			return MountOptsMask{}, fmt.Errorf("invalid sloppy_mount option: %w", err)
+	case PassThroughPrefixedOptions:
+		if m.UnknownOptions.Prefix != "" && strings.HasPrefix(key, m.UnknownOptions.Prefix) {
+			return PassThroughUnknownOptions
 		}
+		return RejectUnknownOptions
 	}
 
-	return mask, nil
+	return m.UnknownOptions.Mode
+}
+
+// LockedPolicy decides what happens when the user supplies a Locked option
+// with a different value. Locked values are always applied after user input.
+type LockedPolicy int
+
+const (
+	OverrideLocked LockedPolicy = iota
+	RejectLockedOverride
+)
+
+// OptionScope is the stage at which an option may be set. Options without a
+// scope in MountOptsMask.Scopes may be set at any stage, and evaluating
+// options at ScopeAny does not check scopes at all.
+type OptionScope string
+
+const (
+	ScopeAny      OptionScope = ""
+	ScopeInstance OptionScope = "instance"
+	ScopeBinding  OptionScope = "binding"
+)
+
+// AliasPrecedence decides which value is used when the user supplies several
+// KeyPerms aliases of the same canonical key, or an alias together with the
+// canonical key, with different values. When several aliases conflict
+// without the canonical key, the alias that sorts first wins.
+type AliasPrecedence int
+
+const (
+	CanonicalWins AliasPrecedence = iota
+	AliasWins
+	RejectAliasConflict
+)
+
+func (p AliasPrecedence) prefers(candidate, current, canonicalKey string) bool {
+	switch p {
+	case CanonicalWins:
+		return candidate == canonicalKey
+	case AliasWins:
+		return current == canonicalKey
+	}
+	return false
+}
+
+// MountOptsValidation validates the fully resolved set of options, so that it
+// can check relationships between options. userOpts is the raw user input.
+// Returning a *Violation or *MountOptsError reports specific keys.
+//
+//counterfeiter:generate . MountOptsValidation
+type MountOptsValidation interface {
+	ValidateMountOpts(ctx context.Context, opts MountOpts, userOpts map[string]interface{}) error
+}
+
+type MountOptsValidationFunc func(context.Context, MountOpts, map[string]interface{}) error
+
+func (v MountOptsValidationFunc) ValidateMountOpts(ctx context.Context, opts MountOpts, userOpts map[string]interface{}) error {
+	return v(ctx, opts, userOpts)
+}
+
+func NewMountOptsMask(allowed []string,
+	defaults map[string]interface{},
+	keyPerms map[string]string,
+	ignored, mandatory []string,
+	f ...UserOptsValidation) (MountOptsMask, error) {
+	return NewMask(
+		WithAllowed(allowed...),
+		WithDefaults(defaults),
+		WithKeyPerms(keyPerms),
+		WithIgnored(ignored...),
+		WithMandatory(mandatory...),
+		WithValidator(f...),
+	)
 }
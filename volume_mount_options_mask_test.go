@@ -1,6 +1,9 @@
 package volume_mount_options_test
 
 import (
+	"context"
+	"errors"
+
 	vmo "code.cloudfoundry.org/volume-mount-options"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -89,6 +92,7 @@ var _ = Describe("VolumeMountOptionsMask", func() {
 
 		Context("when given a set of mandatory options", func() {
 			BeforeEach(func() {
+				allowedOpts = []string{"required1", "required2"}
 				mandatoryOpts = []string{"required1", "required2"}
 			})
 
@@ -140,3 +144,193 @@ var _ = Describe("VolumeMountOptionsMask", func() {
 		})
 	})
 })
+
+var _ = Describe("UnknownOptionPolicy", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		result    *vmo.MountOptsResult
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMountOptsMask([]string{"uid"}, nil, nil, nil, nil)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		userInput = map[string]interface{}{
+			"uid":            "1000",
+			"x-vendor.cache": "on",
+			"bogus":          "x",
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = vmo.EvaluateMountOpts(context.Background(), userInput, mask)
+	})
+
+	It("should reject unknown options by default", func() {
+		Expect(err).To(MatchError("- Not allowed options: bogus, x-vendor.cache\n"))
+	})
+
+	Context("when sloppy mount is set", func() {
+		BeforeEach(func() {
+			mask.SloppyMount = true
+		})
+
+		It("should drop unknown options with a warning", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(Equal(vmo.MountOpts{"uid": "1000"}))
+			Expect(result.Dropped).To(Equal([]string{"bogus", "x-vendor.cache"}))
+			Expect(result.Warnings).To(HaveLen(2))
+		})
+
+		Context("and the policy rejects unknown options", func() {
+			BeforeEach(func() {
+				mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.RejectUnknownOptions}
+			})
+
+			It("should take precedence over sloppy mount", func() {
+				Expect(err).To(MatchError("- Not allowed options: bogus, x-vendor.cache\n"))
+			})
+		})
+	})
+
+	Context("when the policy drops unknown options", func() {
+		BeforeEach(func() {
+			mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.DropUnknownOptions}
+		})
+
+		It("should drop them without a warning", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(Equal(vmo.MountOpts{"uid": "1000"}))
+			Expect(result.Dropped).To(Equal([]string{"bogus", "x-vendor.cache"}))
+			Expect(result.Warnings).To(BeEmpty())
+		})
+	})
+
+	Context("when the policy drops unknown options with a warning", func() {
+		BeforeEach(func() {
+			mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.DropUnknownOptionsWithWarning}
+		})
+
+		It("should drop them with a warning", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Warnings).To(Equal([]string{
+				"option bogus is not allowed and was dropped",
+				"option x-vendor.cache is not allowed and was dropped",
+			}))
+		})
+	})
+
+	Context("when the policy passes unknown options through", func() {
+		BeforeEach(func() {
+			mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.PassThroughUnknownOptions}
+		})
+
+		It("should include them in the options", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(Equal(vmo.MountOpts{"uid": "1000", "x-vendor.cache": "on", "bogus": "x"}))
+			Expect(result.PassedThrough).To(Equal([]string{"bogus", "x-vendor.cache"}))
+		})
+
+		It("should still reject unsafe values", func() {
+			_, err = vmo.NewMountOpts(map[string]interface{}{"bogus": "x,uid=0"}, mask)
+			Expect(err).To(MatchError(ContainSubstring("Unsafe options")))
+		})
+	})
+
+	Context("when the policy passes through options with a prefix", func() {
+		BeforeEach(func() {
+			mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-"}
+		})
+
+		It("should reject the other unknown options", func() {
+			Expect(err).To(MatchError("- Not allowed options: bogus\n"))
+		})
+
+		Context("when every unknown option has the prefix", func() {
+			BeforeEach(func() {
+				delete(userInput, "bogus")
+			})
+
+			It("should pass them through", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Options).To(Equal(vmo.MountOpts{"uid": "1000", "x-vendor.cache": "on"}))
+			})
+		})
+	})
+})
+
+var _ = Describe("Locked options", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		result    *vmo.MountOptsResult
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMountOptsMask(
+			[]string{"uid", "sec", "nosuid"},
+			map[string]interface{}{"sec": "sys"},
+			nil,
+			nil,
+			nil,
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		mask.Locked = map[string]interface{}{
+			"nosuid": true,
+			"nodev":  true,
+			"sec":    "krb5p",
+		}
+		userInput = map[string]interface{}{
+			"uid":    "1000",
+			"sec":    "sys",
+			"nosuid": "true",
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = vmo.EvaluateMountOpts(context.Background(), userInput, mask)
+	})
+
+	It("should apply the locked values over user input and defaults", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Options).To(Equal(vmo.MountOpts{
+			"uid":    "1000",
+			"sec":    "krb5p",
+			"nosuid": true,
+			"nodev":  true,
+		}))
+		Expect(result.Provenance["sec"]).To(Equal(vmo.Provenance{Source: vmo.SourceLocked, Key: "sec", RawValue: "krb5p"}))
+	})
+
+	It("should warn about the overridden user option", func() {
+		Expect(result.Warnings).To(Equal([]string{"option sec is locked and was overridden"}))
+	})
+
+	Context("when the policy rejects overrides", func() {
+		BeforeEach(func() {
+			mask.LockedPolicy = vmo.RejectLockedOverride
+		})
+
+		It("should return an error for options with a different value", func() {
+			Expect(errors.Is(err, vmo.ErrLockedOption)).To(BeTrue())
+			Expect(err).To(MatchError("- Locked options: sec\n"))
+		})
+
+		Context("when the user supplies the locked values", func() {
+			BeforeEach(func() {
+				userInput["sec"] = "krb5p"
+			})
+
+			It("should accept them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Options).To(HaveKeyWithValue("sec", "krb5p"))
+			})
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package volume_mount_options
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Normalizer renders a user supplied value as the string stored in MountOpts.
+type Normalizer func(value interface{}) (string, error)
+
+// DefaultNormalizers is the profile used by mapfs and the nfs drivers. It is
+// applied when MountOptsMask.Normalizers is nil; set an empty map to disable
+// it.
+func DefaultNormalizers() map[string]Normalizer {
+	return map[string]Normalizer{
+		"auto-traverse-mounts": BoolAsInt(),
+		"dircache":             BoolAsInt(),
+	}
+}
+
+// BoolAsInt renders booleans as "1" or "0".
+func BoolAsInt() Normalizer {
+	return func(value interface{}) (string, error) {
+		return uniformData(value, true), nil
+	}
+}
+
+// BoolAsYesNo renders booleans as "yes" or "no".
+func BoolAsYesNo() Normalizer {
+	return func(value interface{}) (string, error) {
+		if b, ok := value.(bool); ok {
+			if b {
+				return "yes", nil
+			}
+			return "no", nil
+		}
+		return uniformData(value, false), nil
+	}
+}
+
+func Lowercase() Normalizer {
+	return func(value interface{}) (string, error) {
+		return strings.ToLower(uniformData(value, false)), nil
+	}
+}
+
+func Trim() Normalizer {
+	return func(value interface{}) (string, error) {
+		return strings.TrimSpace(uniformData(value, false)), nil
+	}
+}
+
+// CanonicalNumber renders numbers without leading zeros, trailing zeros or
+// exponents, so that "007" becomes "7" and "1.50" becomes "1.5".
+func CanonicalNumber() Normalizer {
+	return func(value interface{}) (string, error) {
+		s := uniformData(value, false)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10), nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "", fmt.Errorf("expected a number, got %q", s)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+}
+
+// ChainNormalizers applies normalizers in order, each to the output of the
+// previous one.
+func ChainNormalizers(normalizers ...Normalizer) Normalizer {
+	return func(value interface{}) (string, error) {
+		for _, n := range normalizers {
+			s, err := n(value)
+			if err != nil {
+				return "", err
+			}
+			value = s
+		}
+		return uniformData(value, false), nil
+	}
+}
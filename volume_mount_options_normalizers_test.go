@@ -0,0 +1,103 @@
+package volume_mount_options_test
+
+import (
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Normalizers", func() {
+	DescribeTable("normalizing values",
+		func(n vmo.Normalizer, input interface{}, expected string) {
+			output, err := n(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal(expected))
+		},
+		Entry("BoolAsInt with true", vmo.BoolAsInt(), true, "1"),
+		Entry("BoolAsInt with false", vmo.BoolAsInt(), false, "0"),
+		Entry("BoolAsInt with a string", vmo.BoolAsInt(), "true", "true"),
+		Entry("BoolAsYesNo with true", vmo.BoolAsYesNo(), true, "yes"),
+		Entry("BoolAsYesNo with false", vmo.BoolAsYesNo(), false, "no"),
+		Entry("Lowercase", vmo.Lowercase(), "KRB5P", "krb5p"),
+		Entry("Trim", vmo.Trim(), "  bob ", "bob"),
+		Entry("CanonicalNumber with leading zeros", vmo.CanonicalNumber(), "007", "7"),
+		Entry("CanonicalNumber with trailing zeros", vmo.CanonicalNumber(), "1.50", "1.5"),
+		Entry("CanonicalNumber with a float", vmo.CanonicalNumber(), 4.0, "4"),
+		Entry("ChainNormalizers", vmo.ChainNormalizers(vmo.Trim(), vmo.Lowercase()), " SYS ", "sys"),
+		Entry("ChainNormalizers without normalizers", vmo.ChainNormalizers(), 3, "3"),
+	)
+
+	It("should fail to canonicalize a non-number", func() {
+		_, err := vmo.CanonicalNumber()("banana")
+		Expect(err).To(MatchError(`expected a number, got "banana"`))
+	})
+
+	Context("when used in a mask", func() {
+		var (
+			mask      vmo.MountOptsMask
+			userInput map[string]interface{}
+			actualRes vmo.MountOpts
+			err       error
+		)
+
+		BeforeEach(func() {
+			var maskErr error
+			mask, maskErr = vmo.NewMountOptsMask([]string{"sec", "noac", "dircache", "retrans"}, nil, nil, nil, nil)
+			Expect(maskErr).NotTo(HaveOccurred())
+
+			userInput = map[string]interface{}{
+				"sec":      " KRB5P",
+				"noac":     true,
+				"dircache": true,
+				"retrans":  "03",
+			}
+		})
+
+		JustBeforeEach(func() {
+			actualRes, err = vmo.NewMountOpts(userInput, mask)
+		})
+
+		It("should apply the default profile", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualRes).To(Equal(vmo.MountOpts{
+				"sec":      " KRB5P",
+				"noac":     "true",
+				"dircache": "1",
+				"retrans":  "03",
+			}))
+		})
+
+		Context("given per-key normalizers", func() {
+			BeforeEach(func() {
+				mask.Normalizers = map[string]vmo.Normalizer{
+					"sec":     vmo.ChainNormalizers(vmo.Trim(), vmo.Lowercase()),
+					"noac":    vmo.BoolAsYesNo(),
+					"retrans": vmo.CanonicalNumber(),
+				}
+			})
+
+			It("should only apply those normalizers", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actualRes).To(Equal(vmo.MountOpts{
+					"sec":      "krb5p",
+					"noac":     "yes",
+					"dircache": "true",
+					"retrans":  "3",
+				}))
+			})
+
+			Context("when a normalizer fails", func() {
+				BeforeEach(func() {
+					userInput["retrans"] = "many"
+				})
+
+				It("should return a validation error", func() {
+					Expect(errors.Is(err, vmo.ErrValidationFailed)).To(BeTrue())
+					Expect(err).To(MatchError("- validation mount options failed: retrans: expected a number, got \"many\"\n"))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,192 @@
+package volume_mount_options
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Entries of Allowed, Ignored, Mandatory and Forbidden may be patterns.
+// Entries starting with "^" are regular expressions, anchored at both ends;
+// other entries containing "*", "?" or "[" are globs, matched like
+// path.Match. Everything else matches exactly.
+type keyPattern struct {
+	re *regexp.Regexp
+}
+
+type keyMatch int
+
+const (
+	noMatch keyMatch = iota
+	patternMatch
+	exactMatch
+)
+
+func isPattern(entry string) bool {
+	return strings.HasPrefix(entry, "^") || strings.ContainsAny(entry, "*?[")
+}
+
+func compilePattern(entry string) (keyPattern, error) {
+	if !strings.HasPrefix(entry, "^") {
+		if _, err := path.Match(entry, ""); err != nil {
+			return keyPattern{}, fmt.Errorf("invalid pattern %q: %w", entry, err)
+		}
+	}
+
+	re, err := regexp.Compile(patternExpr(entry))
+	if err != nil {
+		return keyPattern{}, fmt.Errorf("invalid pattern %q: %w", entry, err)
+	}
+	return keyPattern{re: re}, nil
+}
+
+// patternExpr converts a pattern entry to an anchored regular expression.
+func patternExpr(entry string) string {
+	if strings.HasPrefix(entry, "^") {
+		expr := strings.TrimPrefix(entry, "^")
+		if strings.HasSuffix(expr, "$") && !strings.HasSuffix(expr, `\$`) {
+			expr = strings.TrimSuffix(expr, "$")
+		}
+		return "^(?:" + expr + ")$"
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	inClass := false
+	for i := 0; i < len(entry); i++ {
+		c := entry[i]
+		switch {
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			if c == '\\' && i+1 < len(entry) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(entry[i])))
+				continue
+			}
+			b.WriteByte(c)
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+		case c == '\\' && i+1 < len(entry):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(entry[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func (p keyPattern) match(key string) bool {
+	return p.re.MatchString(key)
+}
+
+type keySet struct {
+	exact    map[string]bool
+	patterns []keyPattern
+}
+
+// compileKeySet compiles entries. Invalid patterns are reported in err and
+// left out of the set, which lint still uses.
+func compileKeySet(entries []string) (keySet, error) {
+	s := keySet{exact: make(map[string]bool, len(entries))}
+	var errs []error
+	for _, entry := range entries {
+		s.exact[entry] = true
+		if !isPattern(entry) {
+			continue
+		}
+
+		p, err := compilePattern(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.patterns = append(s.patterns, p)
+	}
+	return s, errors.Join(errs...)
+}
+
+func (s keySet) match(key string) keyMatch {
+	if s.exact[key] {
+		return exactMatch
+	}
+	for _, p := range s.patterns {
+		if p.match(key) {
+			return patternMatch
+		}
+	}
+	return noMatch
+}
+
+func (s keySet) satisfiedBy(opts map[string]interface{}) bool {
+	for k := range s.exact {
+		if _, ok := opts[k]; ok {
+			return true
+		}
+	}
+	if len(s.patterns) == 0 {
+		return false
+	}
+	for k := range opts {
+		if s.match(k) != noMatch {
+			return true
+		}
+	}
+	return false
+}
+
+type forbiddenSet struct {
+	reasons  map[string]string
+	patterns []forbiddenPattern
+}
+
+type forbiddenPattern struct {
+	pattern keyPattern
+	reason  string
+}
+
+// compileForbidden compiles the Forbidden entries like compileKeySet.
+func compileForbidden(forbidden map[string]string) (forbiddenSet, error) {
+	s := forbiddenSet{reasons: forbidden}
+	var errs []error
+	for _, entry := range sortedKeys(forbidden) {
+		if !isPattern(entry) {
+			continue
+		}
+
+		p, err := compilePattern(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.patterns = append(s.patterns, forbiddenPattern{pattern: p, reason: forbidden[entry]})
+	}
+	return s, errors.Join(errs...)
+}
+
+// lookup returns the reason for which key, or its canonical form, is
+// forbidden. Exact entries take priority over patterns.
+func (s forbiddenSet) lookup(key, canonicalKey string) (string, bool) {
+	for _, k := range []string{canonicalKey, key} {
+		if reason, ok := s.reasons[k]; ok {
+			return reason, true
+		}
+	}
+
+	for _, f := range s.patterns {
+		if f.pattern.match(canonicalKey) || f.pattern.match(key) {
+			return f.reason, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,147 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Patterns", func() {
+	var (
+		allowedOpts   []string
+		ignoredOpts   []string
+		mandatoryOpts []string
+		userInput     map[string]interface{}
+		maskErr       error
+		result        *vmo.MountOptsResult
+		err           error
+	)
+
+	BeforeEach(func() {
+		allowedOpts = []string{"uid", "x-systemd.*", `^cache-(size|mode)$`}
+		ignoredOpts = []string{"x-*", "x-systemd.automount"}
+		mandatoryOpts = []string{}
+		userInput = map[string]interface{}{
+			"uid":                 "1000",
+			"x-systemd.device":    "/dev/sda",
+			"x-systemd.automount": "",
+			"x-gvfs-show":         "",
+			"cache-size":          "64",
+		}
+	})
+
+	JustBeforeEach(func() {
+		var mask vmo.MountOptsMask
+		mask, maskErr = vmo.NewMountOptsMask(allowedOpts, nil, nil, ignoredOpts, mandatoryOpts)
+		if maskErr == nil {
+			result, err = vmo.EvaluateMountOpts(context.Background(), userInput, mask)
+		}
+	})
+
+	It("should match keys against globs and regular expressions", func() {
+		Expect(maskErr).NotTo(HaveOccurred())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Options).To(Equal(vmo.MountOpts{
+			"uid":        "1000",
+			"cache-size": "64",
+		}))
+		Expect(result.Ignored).To(Equal([]string{"x-gvfs-show", "x-systemd.automount", "x-systemd.device"}))
+	})
+
+	Context("when an exact entry is allowed", func() {
+		BeforeEach(func() {
+			allowedOpts = append(allowedOpts, "x-systemd.device")
+		})
+
+		It("should take priority over an ignored pattern", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Options).To(HaveKeyWithValue("x-systemd.device", "/dev/sda"))
+			Expect(result.Ignored).To(Equal([]string{"x-gvfs-show", "x-systemd.automount"}))
+		})
+	})
+
+	Context("when a key only partially matches a regular expression", func() {
+		BeforeEach(func() {
+			userInput = map[string]interface{}{"cache-size-max": "64"}
+		})
+
+		It("should not be allowed", func() {
+			Expect(err).To(MatchError("- Not allowed options: cache-size-max\n"))
+		})
+	})
+
+	Context("when a mandatory entry is a pattern", func() {
+		BeforeEach(func() {
+			mandatoryOpts = []string{"^cache-.*"}
+		})
+
+		It("should be satisfied by any matching key", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when no key matches", func() {
+			BeforeEach(func() {
+				delete(userInput, "cache-size")
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError("- Missing mandatory options: ^cache-.*\n"))
+			})
+		})
+	})
+
+	DescribeTable("invalid patterns",
+		func(entry string, expected string) {
+			_, err := vmo.NewMountOptsMask([]string{entry}, nil, nil, nil, nil)
+			Expect(err).To(MatchError(expected))
+
+			_, err = vmo.NewMountOptsMask(nil, nil, nil, []string{entry}, nil)
+			Expect(err).To(HaveOccurred())
+
+			_, err = vmo.NewMountOptsMask(nil, nil, nil, nil, []string{entry})
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("glob", "x-[", `invalid pattern "x-[": syntax error in pattern`),
+		Entry("regular expression", "^cache-(", "invalid pattern \"^cache-(\": error parsing regexp: missing closing ): `^(?:cache-()$`"),
+	)
+
+	Describe("Forbidden", func() {
+		var mask vmo.MountOptsMask
+
+		BeforeEach(func() {
+			mask, maskErr = vmo.NewMountOptsMask(
+				[]string{"uid", "suid"},
+				map[string]interface{}{"sloppy_mount": "true"},
+				map[string]string{"setuid": "suid"},
+				nil,
+				nil,
+			)
+			Expect(maskErr).NotTo(HaveOccurred())
+
+			mask.Forbidden = map[string]string{
+				"suid":   "setuid binaries are not permitted on shared cells",
+				"dev":    "",
+				"x-dev*": "",
+			}
+		})
+
+		It("should reject forbidden options even when they are allowed and sloppy mount is set", func() {
+			_, err := vmo.NewMountOpts(map[string]interface{}{"uid": "1000", "suid": "", "dev": "", "x-device": ""}, mask)
+			Expect(errors.Is(err, vmo.ErrForbiddenOption)).To(BeTrue())
+			Expect(err).To(MatchError("- Forbidden options: dev, suid (setuid binaries are not permitted on shared cells), x-device\n"))
+		})
+
+		It("should reject aliases of forbidden options", func() {
+			_, err := vmo.NewMountOpts(map[string]interface{}{"setuid": ""}, mask)
+
+			var mountOptsErr *vmo.MountOptsError
+			Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+			Expect(mountOptsErr.Violations).To(HaveLen(1))
+			Expect(mountOptsErr.Violations[0].Key).To(Equal("setuid"))
+			Expect(mountOptsErr.Violations[0].Cause).To(MatchError("setuid binaries are not permitted on shared cells"))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package volume_mount_options
+
+import "fmt"
+
+type AliasRewrite struct {
+	Alias     string
+	Canonical string
+}
+
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceUser    Source = "user"
+	SourceLocked  Source = "locked"
+)
+
+// Provenance records where the value of an option came from. Key is the key
+// the value was supplied under, which differs from the option key when the
+// user supplied an alias. RawValue is the value before normalization. Layer
+// names the OptsLayer of user options merged by MergeMountOpts.
+type Provenance struct {
+	Source   Source
+	Key      string
+	RawValue interface{}
+	Layer    string
+}
+
+// MountOptsResult is returned by EvaluateMountOpts. Dropped and
+// PassedThrough list the user keys that were not allowed but were dropped or
+// passed through by the mask's unknown option policy, and Ignored the user
+// keys dropped because the mask ignores them. Provenance has an entry for
+// every key of Options.
+type MountOptsResult struct {
+	Options       MountOpts
+	Dropped       []string
+	PassedThrough []string
+	Ignored       []string
+	Aliases       []AliasRewrite
+	Warnings      []string
+	Provenance    map[string]Provenance
+}
+
+func (r *MountOptsResult) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Explain describes the provenance of each option in key order, one line per
+// option, for example "vers=3: default", "uid=1000: user, via alias UID" or
+// "gid=1000: user (binding)".
+func (r *MountOptsResult) Explain() []string {
+	var lines []string
+	for _, key := range r.Options.Keys() {
+		value := r.Options[key]
+		p := r.Provenance[key]
+
+		line := fmt.Sprintf("%s=%v: %s", key, value, p.Source)
+		if p.Layer != "" {
+			line += " (" + p.Layer + ")"
+		}
+		if p.Key != key {
+			line += ", via alias " + p.Key
+		}
+		if raw := fmt.Sprintf("%v", p.RawValue); raw != fmt.Sprintf("%v", value) {
+			line += fmt.Sprintf(", normalized from %q", raw)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
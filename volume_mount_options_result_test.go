@@ -0,0 +1,132 @@
+package volume_mount_options_test
+
+import (
+	"context"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("#EvaluateMountOpts", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		result    *vmo.MountOptsResult
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMountOptsMask(
+			[]string{"uid", "gid", "vers"},
+			map[string]interface{}{"sloppy_mount": "true"},
+			map[string]string{"UID": "uid", "version": "vers"},
+			[]string{"readonly"},
+			[]string{},
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+
+		userInput = map[string]interface{}{
+			"UID":      "1000",
+			"uid":      "2000",
+			"version":  "4.1",
+			"gid":      "1000",
+			"readonly": true,
+			"bogus":    "x",
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = vmo.EvaluateMountOpts(context.Background(), userInput, mask)
+	})
+
+	It("should return the accepted options", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Options).To(Equal(vmo.MountOpts{
+			"uid":          "2000",
+			"gid":          "1000",
+			"vers":         "4.1",
+			"sloppy_mount": "true",
+		}))
+	})
+
+	It("should list the keys dropped by sloppy mode", func() {
+		Expect(result.Dropped).To(Equal([]string{"bogus"}))
+	})
+
+	It("should list the keys ignored by the mask", func() {
+		Expect(result.Ignored).To(Equal([]string{"readonly"}))
+	})
+
+	It("should list the aliases that supplied a value", func() {
+		Expect(result.Aliases).To(Equal([]vmo.AliasRewrite{
+			{Alias: "version", Canonical: "vers"},
+		}))
+	})
+
+	Context("when aliases take precedence", func() {
+		BeforeEach(func() {
+			mask.AliasPrecedence = vmo.AliasWins
+		})
+
+		It("should list the alias that supplied the value", func() {
+			Expect(result.Aliases).To(Equal([]vmo.AliasRewrite{
+				{Alias: "UID", Canonical: "uid"},
+				{Alias: "version", Canonical: "vers"},
+			}))
+		})
+	})
+
+	It("should warn about dropped and overridden options", func() {
+		Expect(result.Warnings).To(Equal([]string{
+			"option bogus is not allowed and was dropped",
+			"option UID was overridden by uid",
+		}))
+	})
+
+	It("should record the provenance of each option", func() {
+		Expect(result.Provenance).To(Equal(map[string]vmo.Provenance{
+			"uid":          {Source: vmo.SourceUser, Key: "uid", RawValue: "2000"},
+			"gid":          {Source: vmo.SourceUser, Key: "gid", RawValue: "1000"},
+			"vers":         {Source: vmo.SourceUser, Key: "version", RawValue: "4.1"},
+			"sloppy_mount": {Source: vmo.SourceDefault, Key: "sloppy_mount", RawValue: "true"},
+		}))
+	})
+
+	Context("when values are normalized", func() {
+		BeforeEach(func() {
+			mask.Allowed = append(mask.Allowed, "dircache")
+			mask.Defaults = map[string]interface{}{"vers": 3}
+			userInput = map[string]interface{}{"dircache": true, "UID": 1000}
+		})
+
+		It("should explain each option", func() {
+			Expect(result.Explain()).To(Equal([]string{
+				`dircache=1: user, normalized from "true"`,
+				`uid=1000: user, via alias UID`,
+				`vers=3: default`,
+			}))
+		})
+	})
+
+	It("should agree with NewMountOpts", func() {
+		opts, err := vmo.NewMountOpts(userInput, mask)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(result.Options))
+	})
+
+	Context("when validation fails", func() {
+		BeforeEach(func() {
+			mask.SloppyMount = false
+		})
+
+		It("should return the result along with the error", func() {
+			Expect(err).To(MatchError("- Not allowed options: bogus\n"))
+			Expect(result.Options).To(BeEmpty())
+			Expect(result.Ignored).To(Equal([]string{"readonly"}))
+			Expect(result.Dropped).To(BeEmpty())
+			Expect(result.Provenance).To(BeEmpty())
+		})
+	})
+})
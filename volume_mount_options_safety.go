@@ -0,0 +1,55 @@
+package volume_mount_options
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// UnsafeValuePolicy controls what NewMountOpts does with user values that
+// contain the option separator. Keys containing separators, and keys or
+// values containing control characters, are always rejected.
+//
+// AllowUnsafeValues accepts such values unchanged, for consumers that
+// escape them in a way their target understands when serializing. It does
+// not escape anything itself, and the kernel has no such escape, so
+// utils.OptionMapToString refuses them.
+type UnsafeValuePolicy int
+
+const (
+	RejectUnsafeValues UnsafeValuePolicy = iota
+	AllowUnsafeValues
+)
+
+var (
+	errKeySeparator     = errors.New("key contains a separator character")
+	errKeyControl       = errors.New("key contains a control character")
+	errValueSeparator   = errors.New("value contains a separator character")
+	errValueControl     = errors.New("value contains a control character")
+	errEmptyKey         = errors.New("key is empty")
+	optionKeySeparators = ",="
+)
+
+func checkKey(key string) error {
+	if key == "" {
+		return errEmptyKey
+	}
+	if strings.ContainsAny(key, optionKeySeparators) {
+		return errKeySeparator
+	}
+	if strings.IndexFunc(key, unicode.IsControl) >= 0 {
+		return errKeyControl
+	}
+	return nil
+}
+
+func (p UnsafeValuePolicy) sanitize(value string) (string, error) {
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return "", errValueControl
+	}
+
+	if p == RejectUnsafeValues && strings.Contains(value, ",") {
+		return "", errValueSeparator
+	}
+	return value, nil
+}
@@ -0,0 +1,217 @@
+package volume_mount_options
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-04/schema#"
+
+// JSONSchema describes the user options accepted by mask at scope as a JSON
+// Schema (draft-04) object, for use as an OSBAPI plan parameters schema.
+// Forbidden and Locked options are left out, as are options of other
+// scopes. Mandatory options without a default are required, under any of
+// their aliases. Unknown options are only allowed when the mask does not
+// reject them.
+//
+// Options with a Normalizer are only described as scalars, as normalizers
+// can rewrite values before they are checked against their type. Bounds of
+// int, duration and size types only apply to JSON numbers: draft-04 cannot
+// bound numbers supplied as strings, which NewMountOpts still rejects.
+func JSONSchema(mask MountOptsMask, scope OptionScope) map[string]interface{} {
+	properties := make(map[string]interface{})
+	aliases := make(map[string][]string)
+	for _, alias := range sortedKeys(mask.KeyPerms) {
+		canonicalKey := mask.KeyPerms[alias]
+		aliases[canonicalKey] = append(aliases[canonicalKey], alias)
+	}
+
+	inScope := func(key string) bool {
+		want := mask.Scopes[key]
+		return scope == ScopeAny || want == ScopeAny || want == scope
+	}
+	// Invalid patterns match nothing; NewMountOptsMask rejects them.
+	forbiddenSet, _ := compileForbidden(mask.Forbidden)
+	accepts := func(key string) bool {
+		_, locked := mask.Locked[key]
+		_, forbidden := forbiddenSet.lookup(key, key)
+		return !locked && !forbidden && inScope(key)
+	}
+
+	for _, k := range append(append([]string(nil), mask.Allowed...), mask.Ignored...) {
+		if isPattern(k) || !accepts(k) {
+			continue
+		}
+
+		property := mask.propertySchema(k)
+		properties[k] = property
+		for _, alias := range aliases[k] {
+			if _, forbidden := forbiddenSet.lookup(alias, k); !forbidden {
+				properties[alias] = property
+			}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":    jsonSchemaDraft,
+		"type":       "object",
+		"properties": properties,
+	}
+
+	var required []string
+	var requiredAnyOf []interface{}
+	for _, k := range mask.Mandatory {
+		_, hasDefault := mask.Defaults[k]
+		if isPattern(k) || hasDefault || !accepts(k) {
+			continue
+		}
+
+		if len(aliases[k]) == 0 {
+			required = append(required, k)
+			continue
+		}
+		var anyOf []interface{}
+		for _, key := range append([]string{k}, aliases[k]...) {
+			anyOf = append(anyOf, map[string]interface{}{"required": []string{key}})
+		}
+		requiredAnyOf = append(requiredAnyOf, map[string]interface{}{"anyOf": anyOf})
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	if len(requiredAnyOf) > 0 {
+		schema["allOf"] = requiredAnyOf
+	}
+
+	patternProperties := make(map[string]interface{})
+	for _, k := range mask.Allowed {
+		if isPattern(k) {
+			patternProperties[patternExpr(k)] = map[string]interface{}{"type": scalarTypes}
+		}
+	}
+
+	switch mask.UnknownOptions.Mode {
+	case UnknownOptionsDefault:
+		schema["additionalProperties"] = mask.SloppyMount
+	case RejectUnknownOptions:
+		schema["additionalProperties"] = false
+	case PassThroughPrefixedOptions:
+		schema["additionalProperties"] = false
+		if mask.UnknownOptions.Prefix != "" {
+			patternProperties["^"+regexp.QuoteMeta(mask.UnknownOptions.Prefix)] = map[string]interface{}{"type": scalarTypes}
+		}
+	default:
+		schema["additionalProperties"] = true
+	}
+	if len(patternProperties) > 0 {
+		schema["patternProperties"] = patternProperties
+	}
+
+	return schema
+}
+
+var scalarTypes = []string{"string", "number", "boolean"}
+
+func (m MountOptsMask) propertySchema(key string) map[string]interface{} {
+	normalizers := m.Normalizers
+	if normalizers == nil {
+		normalizers = DefaultNormalizers()
+	}
+
+	property := map[string]interface{}{"type": scalarTypes}
+	if t, ok := m.Types[key]; ok {
+		if _, normalized := normalizers[key]; !normalized {
+			property = t.jsonSchema()
+		}
+	}
+	if v, ok := m.Defaults[key]; ok {
+		property["default"] = v
+	}
+	return property
+}
+
+// jsonSchema describes the values t accepts. Numbers may also be supplied as
+// strings, as they are by option strings.
+func (t OptionType) jsonSchema() map[string]interface{} {
+	switch t.Kind {
+	case OptionKindInt:
+		return t.numberSchema(`^[+-]?[0-9]+$`)
+
+	case OptionKindBool:
+		return map[string]interface{}{
+			"type":    []string{"boolean", "string"},
+			"pattern": `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`,
+		}
+
+	case OptionKindEnum:
+		return enumSchema(t.Values)
+
+	case OptionKindString:
+		property := map[string]interface{}{"type": "string"}
+		if t.Pattern != nil {
+			property["pattern"] = t.Pattern.String()
+		}
+		return property
+
+	case OptionKindDuration:
+		return t.numberSchema(durationPattern)
+
+	case OptionKindSize:
+		property := t.numberSchema(sizePattern)
+		if t.Min == nil || *t.Min < 0 {
+			property["minimum"] = 0
+		}
+		return property
+	}
+
+	return map[string]interface{}{"type": scalarTypes}
+}
+
+// durationPattern matches the integers and Go durations accepted by
+// parseDuration, and sizePattern the sizes accepted by parseSize. Durations
+// that are not a whole number of the type's unit still match.
+const (
+	durationPattern = `^[+-]?([0-9]+|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+	sizePattern     = `^\+?[0-9]+([kKmMgGtT][iI]?)?[bB]?$`
+)
+
+// numberSchema describes integers, or strings matching pattern, within the
+// bounds of t.
+func (t OptionType) numberSchema(pattern string) map[string]interface{} {
+	property := map[string]interface{}{"type": []string{"integer", "string"}, "pattern": pattern}
+	if t.Min != nil {
+		property["minimum"] = *t.Min
+	}
+	if t.Max != nil {
+		property["maximum"] = *t.Max
+	}
+	return property
+}
+
+// enumSchema lists values, and the numbers and booleans that are rendered as
+// one of them.
+func enumSchema(values []string) map[string]interface{} {
+	types := []string{"string"}
+	enum := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		enum = append(enum, v)
+	}
+	for _, v := range values {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(n, 10) == v {
+			enum = append(enum, n)
+			types = appendMissing(types, []string{"number"})
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil && uniformData(f, false) == v {
+			enum = append(enum, f)
+			types = appendMissing(types, []string{"number"})
+		} else if b, err := strconv.ParseBool(v); err == nil && strconv.FormatBool(b) == v {
+			enum = append(enum, b)
+			types = appendMissing(types, []string{"boolean"})
+		}
+	}
+	if len(types) == 1 {
+		return map[string]interface{}{"type": "string", "enum": enum}
+	}
+	return map[string]interface{}{"type": types, "enum": enum}
+}
@@ -0,0 +1,236 @@
+package volume_mount_options_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"time"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("#JSONSchema", func() {
+	var (
+		mask  vmo.MountOptsMask
+		scope vmo.OptionScope
+	)
+
+	BeforeEach(func() {
+		var err error
+		mask, err = vmo.NewMask(
+			vmo.WithAllowed("source", "uid", "gid", "vers", "sec", "timeo", "suid", "x-systemd.*"),
+			vmo.WithDefault("vers", "4.1"),
+			vmo.WithAlias("UID", "uid"),
+			vmo.WithIgnored("readonly"),
+			vmo.WithMandatory("source", "uid", "vers"),
+			vmo.WithForbidden("suid", ""),
+			vmo.WithLocked("nodev", true),
+			vmo.WithScope(vmo.ScopeInstance, "source", "vers", "sec"),
+			vmo.WithScope(vmo.ScopeBinding, "uid", "gid"),
+			vmo.WithType("gid", vmo.IntRangeType(0, 65535)),
+			vmo.WithType("sec", vmo.EnumType("sys", "krb5")),
+			vmo.WithType("timeo", vmo.DurationType(0)),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		scope = vmo.ScopeAny
+	})
+
+	schemaJSON := func() string {
+		data, err := json.Marshal(vmo.JSONSchema(mask, scope))
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should describe the options accepted by the mask", func() {
+		Expect(schemaJSON()).To(MatchJSON(`{
+			"$schema": "http://json-schema.org/draft-04/schema#",
+			"type": "object",
+			"properties": {
+				"source": {"type": ["string", "number", "boolean"]},
+				"uid": {"type": ["string", "number", "boolean"]},
+				"UID": {"type": ["string", "number", "boolean"]},
+				"gid": {"type": ["integer", "string"], "pattern": "^[+-]?[0-9]+$", "minimum": 0, "maximum": 65535},
+				"vers": {"type": ["string", "number", "boolean"], "default": "4.1"},
+				"sec": {"type": "string", "enum": ["sys", "krb5"]},
+				"timeo": {"type": ["integer", "string"], "pattern": "^[+-]?([0-9]+|(([0-9]+(\\.[0-9]*)?|\\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$"},
+				"readonly": {"type": ["string", "number", "boolean"]}
+			},
+			"patternProperties": {
+				"^x-systemd\\.[^/]*$": {"type": ["string", "number", "boolean"]}
+			},
+			"required": ["source"],
+			"allOf": [{"anyOf": [{"required": ["uid"]}, {"required": ["UID"]}]}],
+			"additionalProperties": false
+		}`))
+	})
+
+	Context("at binding scope", func() {
+		BeforeEach(func() {
+			scope = vmo.ScopeBinding
+		})
+
+		It("should leave out options of other scopes", func() {
+			schema := vmo.JSONSchema(mask, scope)
+			Expect(schema["properties"]).To(HaveLen(5))
+			Expect(schema["properties"]).To(HaveKey("gid"))
+			Expect(schema["properties"]).NotTo(HaveKey("source"))
+			Expect(schema).NotTo(HaveKey("required"))
+			Expect(schema).To(HaveKey("allOf"))
+		})
+	})
+
+	Context("at instance scope", func() {
+		BeforeEach(func() {
+			scope = vmo.ScopeInstance
+		})
+
+		It("should only require options of that scope", func() {
+			schema := vmo.JSONSchema(mask, scope)
+			Expect(schema["required"]).To(Equal([]string{"source"}))
+			Expect(schema).NotTo(HaveKey("allOf"))
+		})
+	})
+
+	DescribeTable("additionalProperties",
+		func(sloppy bool, policy vmo.UnknownOptionPolicy, expected bool) {
+			mask.SloppyMount = sloppy
+			mask.UnknownOptions = policy
+			Expect(vmo.JSONSchema(mask, scope)["additionalProperties"]).To(Equal(expected))
+		},
+		Entry("by default", false, vmo.UnknownOptionPolicy{}, false),
+		Entry("with sloppy mount", true, vmo.UnknownOptionPolicy{}, true),
+		Entry("when rejecting sloppily", true, vmo.UnknownOptionPolicy{Mode: vmo.RejectUnknownOptions}, false),
+		Entry("when dropping", false, vmo.UnknownOptionPolicy{Mode: vmo.DropUnknownOptions}, true),
+		Entry("when passing through", false, vmo.UnknownOptionPolicy{Mode: vmo.PassThroughUnknownOptions}, true),
+	)
+
+	It("should allow prefixed pass-through options", func() {
+		mask.UnknownOptions = vmo.UnknownOptionPolicy{Mode: vmo.PassThroughPrefixedOptions, Prefix: "x-vendor."}
+		schema := vmo.JSONSchema(mask, scope)
+		Expect(schema["additionalProperties"]).To(BeFalse())
+		Expect(schema["patternProperties"]).To(HaveKey(`^x-vendor\.`))
+	})
+
+	DescribeTable("properties agree with NewMountOpts",
+		func(key string, value interface{}, valid bool) {
+			mask, err := vmo.NewMask(
+				vmo.WithAllowed("vers", "gid", "timeo", "rsize", "proto"),
+				vmo.WithType("vers", vmo.EnumType("3", "4.1")),
+				vmo.WithType("proto", vmo.EnumType("tcp", "udp")),
+				vmo.WithNormalizer("proto", vmo.Lowercase()),
+				vmo.WithType("gid", vmo.IntRangeType(0, 65535)),
+				vmo.WithType("timeo", vmo.DurationType(time.Second)),
+				vmo.WithType("rsize", vmo.SizeType()),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Round-trip through JSON, as a broker would receive both.
+			var schema struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+			}
+			data, err := json.Marshal(vmo.JSONSchema(mask, vmo.ScopeAny))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(data, &schema)).To(Succeed())
+			data, err = json.Marshal(value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(data, &value)).To(Succeed())
+
+			_, err = vmo.NewMountOpts(map[string]interface{}{key: value}, mask)
+			Expect(err == nil).To(Equal(valid), "NewMountOpts: %v", err)
+			Expect(conformsTo(schema.Properties[key], value)).To(Equal(valid), "schema: %v", schema.Properties[key])
+		},
+		Entry("an enum string", "vers", "4.1", true),
+		Entry("an enum integer", "vers", 3, true),
+		Entry("an enum float", "vers", 4.1, true),
+		Entry("a number outside of an enum", "vers", 4, false),
+		Entry("a normalized enum", "proto", "TCP", true),
+		Entry("an integer string", "gid", "1000", true),
+		Entry("an integer out of range", "gid", 70000, false),
+		Entry("a duration", "timeo", "1m30s", true),
+		Entry("a bare duration", "timeo", 600, true),
+		Entry("an invalid duration", "timeo", "soon", false),
+		Entry("a size", "rsize", "64KiB", true),
+		Entry("a bare size", "rsize", 1048576, true),
+		Entry("a size with an unknown unit", "rsize", "64Q", false),
+		Entry("a negative size", "rsize", -1, false),
+	)
+
+	DescribeTable("pattern properties match the keys the mask allows",
+		func(pattern string, matching, other string) {
+			mask.Allowed = []string{pattern}
+			mask.Mandatory = nil
+			patterns := vmo.JSONSchema(mask, scope)["patternProperties"].(map[string]interface{})
+			Expect(patterns).To(HaveLen(1))
+			for expr := range patterns {
+				re := regexp.MustCompile(expr)
+				Expect(re.MatchString(matching)).To(BeTrue())
+				Expect(re.MatchString(other)).To(BeFalse())
+
+				_, err := vmo.NewMountOpts(map[string]interface{}{matching: "1"}, mask)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = vmo.NewMountOpts(map[string]interface{}{other: "1"}, mask)
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("a glob", "x-systemd.*", "x-systemd.automount", "x-systemdXautomount"),
+		Entry("a glob with a class", "opt[0-9]?", "opt1a", "opta1"),
+		Entry("a regexp", `^actimeo|acreg(min|max)$`, "acregmin", "acregmid"),
+	)
+})
+
+// conformsTo checks value against the draft-04 keywords that JSONSchema uses
+// for properties: type, enum, pattern, minimum and maximum.
+func conformsTo(property map[string]interface{}, value interface{}) bool {
+	var valueType string
+	switch v := value.(type) {
+	case string:
+		valueType = "string"
+	case bool:
+		valueType = "boolean"
+	case float64:
+		valueType = "number"
+		if v == float64(int64(v)) {
+			valueType = "integer"
+		}
+	}
+
+	types, ok := property["type"].([]interface{})
+	if !ok {
+		types = []interface{}{property["type"]}
+	}
+	typed := false
+	for _, t := range types {
+		typed = typed || t == valueType || t == "number" && valueType == "integer"
+	}
+	if !typed {
+		return false
+	}
+
+	if enum, ok := property["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, value)
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		if pattern, ok := property["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			return false
+		}
+	}
+
+	if n, ok := value.(float64); ok {
+		if min, ok := property["minimum"].(float64); ok && n < min {
+			return false
+		}
+		if max, ok := property["maximum"].(float64); ok && n > max {
+			return false
+		}
+	}
+	return true
+}
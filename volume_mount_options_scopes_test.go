@@ -0,0 +1,104 @@
+package volume_mount_options_test
+
+import (
+	"context"
+	"errors"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Option scopes", func() {
+	var (
+		mask      vmo.MountOptsMask
+		userInput map[string]interface{}
+		scope     vmo.OptionScope
+		opts      vmo.MountOpts
+		err       error
+	)
+
+	BeforeEach(func() {
+		var maskErr error
+		mask, maskErr = vmo.NewMask(
+			vmo.WithAllowed("source", "vers", "sec", "uid", "gid", "readonly", "timeo"),
+			vmo.WithAlias("version", "vers"),
+			vmo.WithScope(vmo.ScopeInstance, "source", "vers", "sec"),
+			vmo.WithScope(vmo.ScopeBinding, "uid", "gid", "readonly"),
+		)
+		Expect(maskErr).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		opts, err = vmo.NewMountOptsForScope(userInput, mask, scope)
+	})
+
+	Context("at instance scope", func() {
+		BeforeEach(func() {
+			scope = vmo.ScopeInstance
+			userInput = map[string]interface{}{"source": "//server/share", "version": "4.1", "timeo": "600"}
+		})
+
+		It("should accept instance options and options without a scope", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{"source": "//server/share", "vers": "4.1", "timeo": "600"}))
+		})
+
+		Context("when binding options are supplied", func() {
+			BeforeEach(func() {
+				userInput["uid"] = "1000"
+				userInput["readonly"] = "true"
+			})
+
+			It("should reject them with a dedicated error kind", func() {
+				Expect(errors.Is(err, vmo.ErrWrongScope)).To(BeTrue())
+				Expect(errors.Is(err, vmo.ErrNotAllowed)).To(BeFalse())
+				Expect(err).To(MatchError("- Options not settable at this scope: readonly (binding only), uid (binding only)\n"))
+				Expect(opts).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("at binding scope", func() {
+		BeforeEach(func() {
+			scope = vmo.ScopeBinding
+			userInput = map[string]interface{}{"uid": "1000", "version": "3"}
+		})
+
+		It("should reject instance options supplied under an alias", func() {
+			var mountOptsErr *vmo.MountOptsError
+			Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+			Expect(mountOptsErr.ViolationsOf(vmo.ErrWrongScope)).To(HaveLen(1))
+			Expect(mountOptsErr.ViolationsOf(vmo.ErrWrongScope)[0].Key).To(Equal("version"))
+		})
+	})
+
+	Context("at any scope", func() {
+		BeforeEach(func() {
+			scope = vmo.ScopeAny
+			userInput = map[string]interface{}{"uid": "1000", "vers": "3"}
+		})
+
+		It("should not check scopes", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{"uid": "1000", "vers": "3"}))
+		})
+	})
+
+	Describe("MergeMountOpts", func() {
+		It("should check the scope of each layer", func() {
+			_, err := vmo.MergeMountOpts(context.Background(), mask,
+				vmo.OptsLayer{Name: "instance", Scope: vmo.ScopeInstance, Opts: map[string]interface{}{"vers": "4.1"}},
+				vmo.OptsLayer{Name: "binding", Scope: vmo.ScopeBinding, Opts: map[string]interface{}{"uid": "1000", "sec": "krb5"}},
+			)
+			Expect(err).To(MatchError("- Options not settable at this scope: sec (instance only)\n"))
+		})
+	})
+
+	Describe("ComposeMasks", func() {
+		It("should reject layers that give a key different scopes", func() {
+			_, err := vmo.ComposeMasks(mask, vmo.MountOptsMask{Scopes: map[string]vmo.OptionScope{"uid": vmo.ScopeInstance}})
+			Expect(err).To(MatchError("uid has different scopes"))
+		})
+	})
+})
@@ -1,16 +1,22 @@
 package volume_mount_options_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	vmo "code.cloudfoundry.org/volume-mount-options"
+	"code.cloudfoundry.org/volume-mount-options/utils"
 	volumemountoptionsfakes "code.cloudfoundry.org/volume-mount-options/volume-mount-optionsfakes"
 	fuzz "github.com/google/gofuzz"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+type contextKey string
+
 var _ = Describe("VolumeMountOptions", func() {
 	Describe("#NewMountOpts", func() {
 		var (
@@ -19,6 +25,8 @@ var _ = Describe("VolumeMountOptions", func() {
 			ignoredOpts         []string
 			keyPerms            map[string]string
 			mandatoryOpts       []string
+			optionTypes         map[string]vmo.OptionType
+			aliasPrecedence     vmo.AliasPrecedence
 			actualRes           vmo.MountOpts
 			err                 error
 			userInput           map[string]interface{}
@@ -33,6 +41,8 @@ var _ = Describe("VolumeMountOptions", func() {
 			ignoredOpts = []string{}
 			keyPerms = map[string]string{}
 			mandatoryOpts = []string{}
+			optionTypes = nil
+			aliasPrecedence = vmo.CanonicalWins
 
 			userInput = map[string]interface{}{}
 
@@ -50,6 +60,8 @@ var _ = Describe("VolumeMountOptions", func() {
 				mandatoryOpts,
 				validationFuncs...)
 			Expect(err).NotTo(HaveOccurred())
+			mask.Types = optionTypes
+			mask.AliasPrecedence = aliasPrecedence
 
 			actualRes, err = vmo.NewMountOpts(userInput, mask)
 		})
@@ -170,6 +182,8 @@ var _ = Describe("VolumeMountOptions", func() {
 					BeforeEach(func() {
 						fuzzer.Fuzz(&key1)
 						fuzzer.Fuzz(&val1)
+						key1 = "key" + strings.NewReplacer(",", "", "=", "", "*", "", "?", "", "[", "").Replace(key1)
+						val1 = strings.ReplaceAll(val1, ",", "")
 
 						userInput = map[string]interface{}{
 							key1: val1,
@@ -323,6 +337,68 @@ var _ = Describe("VolumeMountOptions", func() {
 				}))
 			})
 
+			Context("when an alias and its canonical key are both supplied", func() {
+				BeforeEach(func() {
+					userInput = map[string]interface{}{
+						"UID": "1000",
+						"uid": "2000",
+						"Uid": "3000",
+					}
+
+					allowedOpts = []string{"uid"}
+					keyPerms = map[string]string{
+						"UID": "uid",
+						"Uid": "uid",
+					}
+				})
+
+				It("should use the canonical value", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(actualRes).To(Equal(vmo.MountOpts{"uid": "2000"}))
+				})
+
+				Context("when aliases take precedence", func() {
+					BeforeEach(func() {
+						aliasPrecedence = vmo.AliasWins
+					})
+
+					It("should use the value of the alias that sorts first", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(actualRes).To(Equal(vmo.MountOpts{"uid": "1000"}))
+					})
+				})
+
+				Context("when conflicts are rejected", func() {
+					BeforeEach(func() {
+						aliasPrecedence = vmo.RejectAliasConflict
+					})
+
+					It("should return an error naming the conflicting keys", func() {
+						Expect(errors.Is(err, vmo.ErrAliasConflict)).To(BeTrue())
+						Expect(err).To(MatchError("- Conflicting aliased options: uid (UID, Uid, uid)\n"))
+
+						var mountOptsErr *vmo.MountOptsError
+						Expect(errors.As(err, &mountOptsErr)).To(BeTrue())
+						Expect(mountOptsErr.Violations).To(HaveLen(1))
+						Expect(mountOptsErr.Violations[0].Related).To(Equal([]string{"UID", "Uid", "uid"}))
+					})
+
+					Context("when the values are the same", func() {
+						BeforeEach(func() {
+							userInput = map[string]interface{}{
+								"UID": 1000,
+								"uid": "1000",
+							}
+						})
+
+						It("should not return an error", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(actualRes).To(Equal(vmo.MountOpts{"uid": "1000"}))
+						})
+					})
+				})
+			})
+
 			Context("when a permuted option is not allowed", func() {
 				BeforeEach(func() {
 					userInput = map[string]interface{}{
@@ -390,7 +466,7 @@ var _ = Describe("VolumeMountOptions", func() {
 		Context("when disallowed options, missing mandatory, and failed validations", func() {
 			BeforeEach(func() {
 				fakeValidationFuncI.ValidateReturns(errors.New("validation error"))
-				allowedOpts = []string{"opt1"}
+				allowedOpts = []string{"opt1", "required1"}
 				userInput = map[string]interface{}{
 					"opt1":       "val1",
 					"notallowed": "foo",
@@ -455,6 +531,241 @@ var _ = Describe("VolumeMountOptions", func() {
 			})
 		})
 
+		Context("given floats decoded from JSON", func() {
+			BeforeEach(func() {
+				userInput = map[string]interface{}{
+					"rsize":   float64(1048576),
+					"vers":    float64(4.1),
+					"actimeo": float32(0.5),
+				}
+				allowedOpts = []string{"rsize", "vers", "actimeo"}
+			})
+
+			It("should render them without exponents", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actualRes).To(Equal(vmo.MountOpts{"rsize": "1048576", "vers": "4.1", "actimeo": "0.5"}))
+			})
+
+			Context("when the option has a size type", func() {
+				BeforeEach(func() {
+					optionTypes = map[string]vmo.OptionType{"rsize": vmo.SizeType()}
+				})
+
+				It("should accept large integers", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(actualRes).To(HaveKeyWithValue("rsize", "1048576"))
+				})
+			})
+		})
+
+		Context("given many options that fail in different ways", func() {
+			BeforeEach(func() {
+				allowedOpts = []string{"a", "b", "c", "d", "e", "m-required", "z-required"}
+				mandatoryOpts = []string{"z-required", "m-required"}
+				userInput = map[string]interface{}{
+					"e":  "val",
+					"a":  "val",
+					"d":  "val",
+					"x2": "val",
+					"c":  "val",
+					"x1": "val",
+					"b":  "val",
+					"x3": "val",
+				}
+				fakeValidationFuncI.ValidateStub = func(key, value string) error {
+					return errors.New(key + " is invalid")
+				}
+			})
+
+			It("should report the errors sorted by key", func() {
+				for i := 0; i < 10; i++ {
+					_, err = vmo.NewMountOpts(userInput, mask)
+					Expect(err).To(MatchError(`- validation mount options failed: a is invalid, b is invalid, c is invalid, d is invalid, e is invalid
+- Not allowed options: x1, x2, x3
+- Missing mandatory options: m-required, z-required
+`))
+				}
+			})
+
+			It("should call the validation funcs in key order", func() {
+				var keys []string
+				for i := 0; i < fakeValidationFuncI.ValidateCallCount(); i++ {
+					key, _ := fakeValidationFuncI.ValidateArgsForCall(i)
+					keys = append(keys, key)
+				}
+				Expect(keys).To(Equal([]string{"a", "b", "c", "d", "e"}))
+			})
+		})
+
+		Context("given options with separator or control characters", func() {
+			BeforeEach(func() {
+				allowedOpts = []string{"username", "password"}
+				userInput = map[string]interface{}{
+					"username": "bob,uid=0",
+					"password": "pass\nword",
+					"uid=0,a":  "",
+				}
+			})
+
+			It("should only let safe options reach the kernel", func() {
+				userInput = map[string]interface{}{"username": `DOMAIN\bob`, "password": `p"ss`}
+				opts, err := vmo.NewMountOpts(userInput, mask)
+				Expect(err).NotTo(HaveOccurred())
+
+				optionString, err := utils.OptionMapToString(opts, "=")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(utils.ParseOptionStringToMap(optionString, "=")).To(Equal(map[string]interface{}(userInput)))
+			})
+
+			It("should reject them", func() {
+				Expect(actualRes).To(Equal(vmo.MountOpts{}))
+				Expect(errors.Is(err, vmo.ErrUnsafeOption)).To(BeTrue())
+				Expect(err).To(MatchError(`- Unsafe options: "password" (value contains a control character), "uid=0,a" (key contains a separator character), "username" (value contains a separator character)
+`))
+			})
+
+			Context("when the mask allows unsafe values", func() {
+				JustBeforeEach(func() {
+					mask.UnsafeValues = vmo.AllowUnsafeValues
+					actualRes, err = vmo.NewMountOpts(map[string]interface{}{
+						"username": `bob,uid=0\`,
+						"password": "secret",
+					}, mask)
+				})
+
+				It("should keep the values unescaped", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(actualRes).To(Equal(vmo.MountOpts{
+						"username": `bob,uid=0\`,
+						"password": "secret",
+					}))
+				})
+
+				It("should not let the values be serialized for the kernel", func() {
+					_, err = utils.OptionMapToString(actualRes, "=")
+					Expect(err).To(MatchError(`cannot represent option "username": value contains a comma`))
+				})
+
+				It("should still reject control characters", func() {
+					_, err = vmo.NewMountOpts(map[string]interface{}{"username": "bob\x00"}, mask)
+					Expect(err).To(MatchError(ContainSubstring("value contains a control character")))
+				})
+			})
+		})
+
+		Context("given validations of the whole option set", func() {
+			var fakeOptsValidation *volumemountoptionsfakes.FakeMountOptsValidation
+
+			BeforeEach(func() {
+				allowedOpts = []string{"username", "uid"}
+				keyPerms = map[string]string{"user": "username"}
+				defaultOpts = map[string]interface{}{"timeo": 600}
+				userInput = map[string]interface{}{
+					"user": "bob",
+					"uid":  1000,
+				}
+
+				fakeOptsValidation = &volumemountoptionsfakes.FakeMountOptsValidation{}
+			})
+
+			JustBeforeEach(func() {
+				mask.OptsValidationFunc = []vmo.MountOptsValidation{fakeOptsValidation}
+				actualRes, err = vmo.NewMountOptsWithContext(context.WithValue(context.Background(), contextKey("key"), "value"), userInput, mask)
+			})
+
+			It("should pass the resolved options, the raw user input and the context", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeOptsValidation.ValidateMountOptsCallCount()).To(Equal(1))
+
+				ctx, opts, rawOpts := fakeOptsValidation.ValidateMountOptsArgsForCall(0)
+				Expect(ctx.Value(contextKey("key"))).To(Equal("value"))
+				Expect(opts).To(Equal(vmo.MountOpts{"username": "bob", "uid": "1000", "timeo": 600}))
+				Expect(rawOpts).To(Equal(userInput))
+			})
+
+			Context("when the validation fails", func() {
+				BeforeEach(func() {
+					fakeOptsValidation.ValidateMountOptsReturns(errors.New("uid is only allowed with username"))
+				})
+
+				It("should return a validation error", func() {
+					Expect(actualRes).To(Equal(vmo.MountOpts{}))
+					Expect(errors.Is(err, vmo.ErrValidationFailed)).To(BeTrue())
+					Expect(err).To(MatchError("- validation mount options failed: uid is only allowed with username\n"))
+				})
+			})
+
+			Context("when the validation reports a violation for a specific key", func() {
+				BeforeEach(func() {
+					fakeOptsValidation.ValidateMountOptsReturns(&vmo.Violation{Kind: vmo.ErrNotAllowed, Key: "uid"})
+				})
+
+				It("should include the violation as is", func() {
+					Expect(err).To(MatchError("- Not allowed options: uid\n"))
+				})
+			})
+
+			Context("when the context is cancelled", func() {
+				JustBeforeEach(func() {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					actualRes, err = vmo.NewMountOptsWithContext(ctx, userInput, mask)
+				})
+
+				It("should return the context error", func() {
+					Expect(err).To(MatchError(context.Canceled))
+				})
+			})
+		})
+
+		Context("given typed options", func() {
+			BeforeEach(func() {
+				allowedOpts = []string{"rsize", "actimeo", "vers", "untyped"}
+				optionTypes = map[string]vmo.OptionType{
+					"rsize":   vmo.SizeType(),
+					"actimeo": vmo.DurationType(time.Second),
+					"vers":    vmo.EnumType("3", "4.1"),
+				}
+				userInput = map[string]interface{}{
+					"rsize":   "1M",
+					"actimeo": 30,
+					"vers":    "4.1",
+					"untyped": "banana",
+				}
+			})
+
+			It("should coerce the values to their declared types", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(actualRes).To(Equal(vmo.MountOpts{
+					"rsize":   "1048576",
+					"actimeo": "30",
+					"vers":    "4.1",
+					"untyped": "banana",
+				}))
+			})
+
+			It("should pass the coerced values to the validation funcs", func() {
+				Expect(fakeValidationFuncI.ValidateCallCount()).To(Equal(4))
+				var values []string
+				for i := 0; i < 4; i++ {
+					_, value := fakeValidationFuncI.ValidateArgsForCall(i)
+					values = append(values, value)
+				}
+				Expect(values).To(ContainElement("1048576"))
+			})
+
+			Context("when a value does not match its type", func() {
+				BeforeEach(func() {
+					userInput["rsize"] = "banana"
+				})
+
+				It("should return an error", func() {
+					Expect(actualRes).To(Equal(vmo.MountOpts{}))
+					Expect(err).To(MatchError("- validation mount options failed: rsize: expected a byte size, got \"banana\"\n"))
+				})
+			})
+		})
+
 		Context("given a default option that is not allowed", func() {
 			BeforeEach(func() {
 				userInput = map[string]interface{}{}
@@ -472,3 +783,12 @@ var _ = Describe("VolumeMountOptions", func() {
 		})
 	})
 })
+
+var _ = Describe("MountOpts", func() {
+	Describe("#Keys", func() {
+		It("should return the keys in sorted order", func() {
+			opts := vmo.MountOpts{"vers": "4.1", "uid": "1000", "gid": "1000", "actimeo": "30"}
+			Expect(opts.Keys()).To(Equal([]string{"actimeo", "gid", "uid", "vers"}))
+		})
+	})
+})
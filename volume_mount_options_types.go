@@ -0,0 +1,183 @@
+package volume_mount_options
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OptionKind string
+
+const (
+	OptionKindInt      OptionKind = "int"
+	OptionKindBool     OptionKind = "bool"
+	OptionKindEnum     OptionKind = "enum"
+	OptionKindString   OptionKind = "string"
+	OptionKindDuration OptionKind = "duration"
+	OptionKindSize     OptionKind = "size"
+)
+
+// OptionType declares the type of an allowed option. Min and Max bound the
+// coerced numeric value of int, duration and size options.
+type OptionType struct {
+	Kind    OptionKind
+	Min     *int64
+	Max     *int64
+	Values  []string
+	Pattern *regexp.Regexp
+	Unit    time.Duration
+}
+
+func IntType() OptionType {
+	return OptionType{Kind: OptionKindInt}
+}
+
+func IntRangeType(min, max int64) OptionType {
+	return OptionType{Kind: OptionKindInt, Min: &min, Max: &max}
+}
+
+// BoolType accepts the values of strconv.ParseBool and coerces them to "true"
+// or "false". A normalizer for the option, such as BoolAsInt, renders them.
+func BoolType() OptionType {
+	return OptionType{Kind: OptionKindBool}
+}
+
+func EnumType(values ...string) OptionType {
+	return OptionType{Kind: OptionKindEnum, Values: values}
+}
+
+func StringType(pattern *regexp.Regexp) OptionType {
+	return OptionType{Kind: OptionKindString, Pattern: pattern}
+}
+
+// DurationType accepts Go duration strings such as "30s" and coerces them to
+// a whole number of unit. Bare integers are taken to already be in unit.
+func DurationType(unit time.Duration) OptionType {
+	return OptionType{Kind: OptionKindDuration, Unit: unit}
+}
+
+// SizeType accepts byte sizes such as "64K" or "1MiB" and coerces them to a
+// number of bytes.
+func SizeType() OptionType {
+	return OptionType{Kind: OptionKindSize}
+}
+
+func (t OptionType) Coerce(value string) (string, error) {
+	switch t.Kind {
+	case OptionKindInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("expected an integer, got %q", value)
+		}
+		return t.checkRange(n)
+
+	case OptionKindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("expected a boolean, got %q", value)
+		}
+		return strconv.FormatBool(b), nil
+
+	case OptionKindEnum:
+		if !inArray(t.Values, value) {
+			return "", fmt.Errorf("expected one of [%s], got %q", strings.Join(t.Values, ", "), value)
+		}
+		return value, nil
+
+	case OptionKindString:
+		if t.Pattern != nil && !t.Pattern.MatchString(value) {
+			return "", fmt.Errorf("%q does not match %s", value, t.Pattern.String())
+		}
+		return value, nil
+
+	case OptionKindDuration:
+		n, err := parseDuration(value, t.Unit)
+		if err != nil {
+			return "", err
+		}
+		return t.checkRange(n)
+
+	case OptionKindSize:
+		n, err := parseSize(value)
+		if err != nil {
+			return "", err
+		}
+		return t.checkRange(n)
+	}
+
+	return "", fmt.Errorf("unknown option type %q", t.Kind)
+}
+
+func (t OptionType) lint() error {
+	switch t.Kind {
+	case OptionKindInt, OptionKindBool, OptionKindString, OptionKindDuration, OptionKindSize:
+	case OptionKindEnum:
+		if len(t.Values) == 0 {
+			return fmt.Errorf("enum type has no values")
+		}
+	default:
+		return fmt.Errorf("unknown option type %q", t.Kind)
+	}
+
+	if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+		return fmt.Errorf("minimum %d is greater than maximum %d", *t.Min, *t.Max)
+	}
+	return nil
+}
+
+func (t OptionType) checkRange(n int64) (string, error) {
+	if t.Min != nil && n < *t.Min {
+		return "", fmt.Errorf("%d is less than the minimum of %d", n, *t.Min)
+	}
+	if t.Max != nil && n > *t.Max {
+		return "", fmt.Errorf("%d is greater than the maximum of %d", n, *t.Max)
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+func parseDuration(value string, unit time.Duration) (int64, error) {
+	if unit <= 0 {
+		unit = time.Second
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration, got %q", value)
+	}
+	if d%unit != 0 {
+		return 0, fmt.Errorf("%q is not a whole number of %s", value, unit)
+	}
+	return int64(d / unit), nil
+}
+
+var sizeMultipliers = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+func parseSize(value string) (int64, error) {
+	digits := strings.TrimRightFunc(value, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	suffix := strings.ToLower(value[len(digits):])
+	suffix = strings.TrimSuffix(suffix, "b")
+	if len(suffix) == 2 {
+		suffix = strings.TrimSuffix(suffix, "i")
+	}
+
+	multiplier, ok := sizeMultipliers[suffix]
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if !ok || err != nil || n < 0 || n > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("expected a byte size, got %q", value)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,108 @@
+package volume_mount_options_test
+
+import (
+	"regexp"
+	"time"
+
+	vmo "code.cloudfoundry.org/volume-mount-options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OptionType", func() {
+	DescribeTable("#Coerce with valid values",
+		func(t vmo.OptionType, input string, expected string) {
+			output, err := t.Coerce(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal(expected))
+		},
+		Entry("int", vmo.IntType(), "32768", "32768"),
+		Entry("int with leading zeros", vmo.IntType(), "007", "7"),
+		Entry("int within range", vmo.IntRangeType(1, 10), "10", "10"),
+		Entry("bool", vmo.BoolType(), "true", "true"),
+		Entry("bool as int", vmo.BoolType(), "0", "false"),
+		Entry("bool in title case", vmo.BoolType(), "True", "true"),
+		Entry("enum", vmo.EnumType("3", "4.1"), "4.1", "4.1"),
+		Entry("string without pattern", vmo.StringType(nil), "anything", "anything"),
+		Entry("string matching pattern", vmo.StringType(regexp.MustCompile(`^krb5[ip]?$`)), "krb5p", "krb5p"),
+		Entry("duration in seconds", vmo.DurationType(time.Second), "1m30s", "90"),
+		Entry("duration in deciseconds", vmo.DurationType(100*time.Millisecond), "1.5s", "15"),
+		Entry("duration as a bare integer", vmo.DurationType(time.Second), "60", "60"),
+		Entry("size in bytes", vmo.SizeType(), "4096", "4096"),
+		Entry("size with a suffix", vmo.SizeType(), "64K", "65536"),
+		Entry("size with a binary suffix", vmo.SizeType(), "1MiB", "1048576"),
+	)
+
+	DescribeTable("#Coerce with invalid values",
+		func(t vmo.OptionType, input string, expected string) {
+			_, err := t.Coerce(input)
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("int", vmo.IntType(), "banana", `expected an integer, got "banana"`),
+		Entry("int below range", vmo.IntRangeType(1, 10), "0", "0 is less than the minimum of 1"),
+		Entry("int above range", vmo.IntRangeType(1, 10), "11", "11 is greater than the maximum of 10"),
+		Entry("bool", vmo.BoolType(), "maybe", `expected a boolean, got "maybe"`),
+		Entry("enum", vmo.EnumType("3", "4.1"), "2", `expected one of [3, 4.1], got "2"`),
+		Entry("string not matching pattern", vmo.StringType(regexp.MustCompile(`^krb5[ip]?$`)), "sys", `"sys" does not match ^krb5[ip]?$`),
+		Entry("duration", vmo.DurationType(time.Second), "soon", `expected a duration, got "soon"`),
+		Entry("duration not a whole unit", vmo.DurationType(time.Second), "1500ms", `"1500ms" is not a whole number of 1s`),
+		Entry("size", vmo.SizeType(), "1X", `expected a byte size, got "1X"`),
+		Entry("negative size", vmo.SizeType(), "-1", `expected a byte size, got "-1"`),
+		Entry("unknown kind", vmo.OptionType{Kind: "complex"}, "1", `unknown option type "complex"`),
+	)
+
+	Describe("typed defaults and locked values", func() {
+		It("should coerce them like user values", func() {
+			mask, err := vmo.NewMask(
+				vmo.WithAllowed("rsize", "timeo"),
+				vmo.WithDefault("rsize", "64K"),
+				vmo.WithLocked("timeo", "1m"),
+				vmo.WithType("rsize", vmo.SizeType()),
+				vmo.WithType("timeo", vmo.DurationType(time.Second)),
+				vmo.WithLockedPolicy(vmo.RejectLockedOverride),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{"timeo": "60"}, mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{"rsize": "65536", "timeo": "60"}))
+		})
+
+		It("should compare booleans by value", func() {
+			mask, err := vmo.NewMask(
+				vmo.WithAllowed("ro", "dircache"),
+				vmo.WithLocked("ro", true),
+				vmo.WithType("ro", vmo.BoolType()),
+				vmo.WithType("dircache", vmo.BoolType()),
+				vmo.WithLockedPolicy(vmo.RejectLockedOverride),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			opts, err := vmo.NewMountOpts(map[string]interface{}{"ro": "1", "dircache": "T"}, mask)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(vmo.MountOpts{"ro": "true", "dircache": "1"}))
+		})
+
+		It("should be reported by the linter when they do not coerce", func() {
+			_, err := vmo.NewMask(
+				vmo.WithAllowed("rsize", "timeo"),
+				vmo.WithDefault("rsize", "banana"),
+				vmo.WithLocked("timeo", "soon"),
+				vmo.WithType("rsize", vmo.IntType()),
+				vmo.WithType("timeo", vmo.DurationType(time.Second)),
+			)
+			Expect(err).To(MatchError(`default of rsize: expected an integer, got "banana"; locked value of timeo: expected a duration, got "soon"`))
+		})
+
+		It("should make evaluation fail when they do not coerce", func() {
+			mask := vmo.MountOptsMask{
+				Allowed:  []string{"rsize"},
+				Defaults: map[string]interface{}{"rsize": "banana"},
+				Types:    map[string]vmo.OptionType{"rsize": vmo.IntType()},
+			}
+
+			_, err := vmo.NewMountOpts(map[string]interface{}{}, mask)
+			Expect(err).To(MatchError(`invalid default for rsize: expected an integer, got "banana"`))
+		})
+	})
+})